@@ -2,6 +2,8 @@ package main
 
 import (
 	"os"
+	// embed the timezone database for IANA timezone names in the server timezone setting.
+	_ "time/tzdata"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/datasource"
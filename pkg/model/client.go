@@ -1,14 +1,26 @@
 package model
 
 import (
+	"context"
 	"time"
 
+	"github.com/cockroachdb/errors"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 )
 
+// FiapApiClient requests the FIAP server. The requests are aborted when the context is done.
 type FiapApiClient interface {
-	CheckHealth() (*backend.CheckHealthResult, error)
-	FetchWithDateRange(resp *backend.DataResponse, dataRange DataRangeType, fromTime *time.Time, toTime *time.Time, pointIDs []PointID, query *backend.DataQuery) error
+	CheckHealth(ctx context.Context) (*backend.CheckHealthResult, error)
+	FetchWithDateRange(ctx context.Context, resp *backend.DataResponse, dataRange DataRangeType, fromTime *time.Time, toTime *time.Time, pointIDs []PointID, query *backend.DataQuery, options *QueryOptions) error
+	// FetchChildren returns the IDs of the point sets and the points directly under the point set.
+	// The error is marked ErrPointNotFound when the server does not know the ID, or ErrNotPointSet when the ID is a point.
+	FetchChildren(ctx context.Context, pointSetID string) (pointSetIDs []string, pointIDs []string, err error)
 }
 
+// ErrPointNotFound marks the errors of IDs unknown to the FIAP server.
+var ErrPointNotFound = errors.New("point not found")
+
+// ErrNotPointSet marks the errors of IDs which are not point sets.
+var ErrNotPointSet = errors.New("not a point set")
+
 type FiapApiClientCreator func(settings *FiapDatasourceSettings) (FiapApiClient, error)
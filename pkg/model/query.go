@@ -2,7 +2,10 @@ package model
 
 import (
 	"fmt"
+	"regexp"
 	"time"
+
+	"github.com/cockroachdb/errors"
 )
 
 type FiapQuery struct {
@@ -10,10 +13,280 @@ type FiapQuery struct {
 	DataRange DataRangeType `json:"data_range"`
 	StartTime LinkedTime    `json:"start_time"`
 	EndTime   LinkedTime    `json:"end_time"`
+	// TimeShift moves the time range into the past, like `1w` (see ParseTimeShift).
+	// Timestamps of the result are moved forward by the same amount so that they line up with other queries.
+	TimeShift string `json:"time_shift,omitempty"`
+	// Timezone overrides the server timezone of the datasource settings for this query.
+	Timezone string `json:"timezone,omitempty"`
+	// ScopedVars are the template variables interpolated into the point IDs and aliases.
+	// The frontend forwards the dashboard variables and the scoped variables in applyTemplateVariables.
+	ScopedVars map[string]ScopedVar `json:"scoped_vars,omitempty"`
+	// Transform converts the values of cumulative counters such as energy meters into the consumption.
+	Transform TransformType `json:"transform,omitempty"`
+	// RateUnit is the unit of time of the rate transform, which is `s`, `m` or `h` (see ParseRateUnit).
+	RateUnit string `json:"rate_unit,omitempty"`
+	// CounterMaxValue is the value where the counter rolls over to zero.
+	// A decrease of the counter is regarded as a reset to zero when it is not set,
+	// or when the previous value is farther from it than from the decreased value.
+	CounterMaxValue *float64 `json:"counter_max_value,omitempty"`
+	// Aggregation aggregates the values of each point into buckets of AggregationInterval.
+	Aggregation AggregationType `json:"aggregation,omitempty"`
+	// AggregationInterval is the width of the buckets like `15m` or `1d` (see ParseInterval).
+	// The interval of the query given by Grafana is used when it is empty.
+	AggregationInterval string `json:"aggregation_interval,omitempty"`
+	// Fill fills the gaps between values which are farther apart than FillThreshold (see FillMode).
+	Fill FillMode `json:"fill,omitempty"`
+	// FillThreshold is the minimum width of gaps like `10m` (see ParseInterval), which must be positive.
+	// The median spacing of the values multiplied by DefaultFillThresholdFactor is used when it is empty.
+	FillThreshold string `json:"fill_threshold,omitempty"`
+	// FrameLayout is the shape of the frames of the response (see FrameLayout).
+	FrameLayout FrameLayout `json:"frame_layout,omitempty"`
+	// JoinRounding truncates the timestamps like `1s` before joining the points into the wide frame (see ParseInterval),
+	// so that samples of nearly the same time share a row.
+	JoinRounding string `json:"join_rounding,omitempty"`
+	// Downsampling reduces the values of the series having more values than the max data points of the query.
+	Downsampling DownsamplingType `json:"downsampling,omitempty"`
+
+	QueryOptions
+}
+
+// QueryOptions are the options of a query which are handled by FiapApiClient.
+type QueryOptions struct {
+	// ExpandPointSets fetches the points under the requested point sets recursively,
+	// instead of failing on point sets.
+	ExpandPointSets bool `json:"expand_point_sets,omitempty"`
+	// NullableNumbers converts values into nullable numbers, leaving null for the values which are not numbers,
+	// instead of converting all values of the point into strings.
+	NullableNumbers bool `json:"nullable_numbers,omitempty"`
+	// KeepRejectedValues adds a string field holding the values which are not numbers, when NullableNumbers is set.
+	KeepRejectedValues bool `json:"keep_rejected_values,omitempty"`
+	// AliasTemplate is the display name of the points without aliases, such as `{{segment -2}} {{segment -1}}`.
+	// Tokens are {{point_id}}, {{ref_id}}, {{segment N}} (negative N counts from the end), {{label KEY}},
+	// and {{$N}} or {{$NAME}} for the capture groups of AliasPattern matched against the point ID.
+	AliasTemplate string `json:"alias_template,omitempty"`
+	// AliasPattern is the regular expression whose capture groups are used in AliasTemplate.
+	AliasPattern string `json:"alias_pattern,omitempty"`
+}
+
+func (o *QueryOptions) Validate() error {
+	if _, err := regexp.Compile(o.AliasPattern); err != nil {
+		return errors.Wrapf(err, "invalid alias pattern '%s'", o.AliasPattern)
+	}
+	return nil
+}
+
+// ScopedVar is a template variable given by Grafana.
+// Value is a string, or an array of strings for multi-value variables.
+type ScopedVar struct {
+	Text  interface{} `json:"text"`
+	Value interface{} `json:"value"`
 }
 
+// GetValues returns the values of the variable as strings.
+func (v *ScopedVar) GetValues() []string {
+	switch value := v.Value.(type) {
+	case nil:
+		return []string{}
+	case string:
+		return []string{value}
+	case []interface{}:
+		values := make([]string, len(value))
+		for i := range value {
+			values[i] = fmt.Sprint(value[i])
+		}
+		return values
+	default:
+		return []string{fmt.Sprint(value)}
+	}
+}
+
+// PointID is a FIAP point requested by the query.
+// DataRange, StartTime and EndTime override the values of the query for this point when they are set,
+// and Alias is shown as the display name of the series instead of the point ID.
 type PointID struct {
-	Value string `json:"point_id"`
+	Value     string        `json:"point_id"`
+	DataRange DataRangeType `json:"data_range,omitempty"`
+	StartTime *LinkedTime   `json:"start_time,omitempty"`
+	EndTime   *LinkedTime   `json:"end_time,omitempty"`
+	Alias     string        `json:"alias,omitempty"`
+	// ValueMapping converts the values of the point, overriding the default of the datasource settings.
+	ValueMapping *ValueMapping `json:"value_mapping,omitempty"`
+	// PointUnit overrides the unit of the point registered in the datasource settings.
+	PointUnit
+
+	// FromTime and ToTime are StartTime and EndTime resolved by the datasource.
+	// They are used only when StartTime or EndTime is set.
+	FromTime *time.Time `json:"-"`
+	ToTime   *time.Time `json:"-"`
+}
+
+// GetDataRange returns the data range of the point, or defaultRange when the point does not override it.
+func (p *PointID) GetDataRange(defaultRange DataRangeType) DataRangeType {
+	if p.DataRange == "" {
+		return defaultRange
+	}
+	return p.DataRange
+}
+
+// GetTimeRange returns the time range of the point, or the given range when the point does not override it.
+func (p *PointID) GetTimeRange(fromTime *time.Time, toTime *time.Time) (*time.Time, *time.Time) {
+	if p.StartTime != nil {
+		fromTime = p.FromTime
+	}
+	if p.EndTime != nil {
+		toTime = p.ToTime
+	}
+	return fromTime, toTime
+}
+
+// ValueMapping converts string values of status points into booleans or numeric codes.
+// Values are matched ignoring case and surrounding spaces, and values not matched become null.
+type ValueMapping struct {
+	Type ValueMappingType `json:"type"`
+	// True and False are the values converted into true and false by the boolean mapping.
+	True  []string `json:"true,omitempty"`
+	False []string `json:"false,omitempty"`
+	// Codes are the numeric codes of the values for the enum mapping.
+	Codes map[string]float64 `json:"codes,omitempty"`
+}
+
+type ValueMappingType string
+
+const BooleanMapping ValueMappingType = "boolean"
+const EnumMapping ValueMappingType = "enum"
+
+func (m *ValueMapping) Validate() error {
+	switch m.Type {
+	case BooleanMapping:
+		if len(m.True) == 0 && len(m.False) == 0 {
+			return errors.New("boolean mapping needs true or false values")
+		}
+	case EnumMapping:
+		if len(m.Codes) == 0 {
+			return errors.New("enum mapping needs codes")
+		}
+	default:
+		return errors.Newf("unknown value mapping type '%s'", m.Type)
+	}
+	return nil
+}
+
+// TransformType is the conversion of the values of counters.
+type TransformType string
+
+// TransformDelta is the difference from the previous value.
+const TransformDelta TransformType = "delta"
+
+// TransformRate is the difference from the previous value per unit of time.
+const TransformRate TransformType = "rate"
+
+// TransformIncrease is the sum of the differences in each bucket of the aggregation interval.
+const TransformIncrease TransformType = "increase"
+
+func (t TransformType) Validate() error {
+	switch t {
+	case TransformDelta, TransformRate, TransformIncrease:
+		return nil
+	default:
+		return errors.Newf("unknown transform '%s'", t)
+	}
+}
+
+// ParseRateUnit returns the duration of the unit of rates, which is a second when the unit is empty.
+func ParseRateUnit(unit string) (time.Duration, error) {
+	switch unit {
+	case "", "s":
+		return time.Second, nil
+	case "m":
+		return time.Minute, nil
+	case "h":
+		return time.Hour, nil
+	default:
+		return 0, errors.Newf("unknown rate unit '%s'", unit)
+	}
+}
+
+// AggregationType is the function aggregating the values in a bucket.
+type AggregationType string
+
+const AggregationAvg AggregationType = "avg"
+const AggregationMin AggregationType = "min"
+const AggregationMax AggregationType = "max"
+const AggregationSum AggregationType = "sum"
+const AggregationCount AggregationType = "count"
+const AggregationFirst AggregationType = "first"
+const AggregationLast AggregationType = "last"
+
+func (a AggregationType) Validate() error {
+	switch a {
+	case AggregationAvg, AggregationMin, AggregationMax, AggregationSum, AggregationCount, AggregationFirst, AggregationLast:
+		return nil
+	default:
+		return errors.Newf("unknown aggregation '%s'", a)
+	}
+}
+
+// FrameLayout is the shape of the frames of the response.
+type FrameLayout string
+
+// MultiFrameLayout returns a frame per point, which is the default.
+const MultiFrameLayout FrameLayout = "multi"
+
+// WideFrameLayout outer-joins all points into a frame on a shared time field.
+const WideFrameLayout FrameLayout = "wide"
+
+// LongFrameLayout returns a frame of the columns time, point_id and value, with a column for each label.
+const LongFrameLayout FrameLayout = "long"
+
+func (l FrameLayout) Validate() error {
+	switch l {
+	case "", MultiFrameLayout, WideFrameLayout, LongFrameLayout:
+		return nil
+	default:
+		return errors.Newf("unknown frame layout '%s'", l)
+	}
+}
+
+// FillMode is the values inserted into gaps.
+type FillMode string
+
+// FillNull inserts a null, which breaks the line of the graph.
+const FillNull FillMode = "null"
+
+// FillPrevious carries the previous value forward.
+const FillPrevious FillMode = "previous"
+
+// FillLinear interpolates between the values of both sides of the gap.
+const FillLinear FillMode = "linear"
+
+// DefaultFillThresholdFactor is multiplied by the median spacing of values to get the default threshold of gaps.
+const DefaultFillThresholdFactor = 3
+
+func (m FillMode) Validate() error {
+	switch m {
+	case FillNull, FillPrevious, FillLinear:
+		return nil
+	default:
+		return errors.Newf("unknown fill mode '%s'", m)
+	}
+}
+
+// DownsamplingType is the algorithm selecting the values kept by downsampling.
+type DownsamplingType string
+
+// DownsamplingLTTB keeps the shape of the series by Largest-Triangle-Three-Buckets.
+const DownsamplingLTTB DownsamplingType = "lttb"
+
+// DownsamplingMinMax keeps the minimum and the maximum values of each pixel.
+const DownsamplingMinMax DownsamplingType = "minmax"
+
+func (d DownsamplingType) Validate() error {
+	switch d {
+	case DownsamplingLTTB, DownsamplingMinMax:
+		return nil
+	default:
+		return errors.Newf("unknown downsampling '%s'", d)
+	}
 }
 
 type DataRangeType string
@@ -29,20 +302,20 @@ type LinkedTime struct {
 
 const frontendDatetimeLayout = "2006-01-02 15:04:05"
 
-func (l *LinkedTime) GetTime(serverTimezone string) (*time.Time, error) {
+// GetTime returns the time entered in the query, evaluated in the location of the context.
+// The time is either the `2006-01-02 15:04:05` layout or a relative time expression such as `now-7d` or `$__to/d`.
+// roundUp is true for end times, which rounds relative time expressions to the end of the unit.
+func (l *LinkedTime) GetTime(tc *TimeContext, roundUp bool) (*time.Time, error) {
 	if l.RawTime == "" {
 		return nil, nil
-	} else if serverTimezone != "" {
-		if dt, err := time.Parse(
-			fmt.Sprintf("%s %s", frontendDatetimeLayout, serverTimezoneLayout),
-			fmt.Sprintf("%s %s", l.RawTime, serverTimezone),
-		); err == nil {
+	} else if isRelativeTime(l.RawTime) {
+		if dt, err := parseRelativeTime(l.RawTime, tc, roundUp); err == nil {
 			return &dt, nil
 		} else {
 			return nil, err
 		}
 	} else {
-		if dt, err := time.Parse(frontendDatetimeLayout, l.RawTime); err == nil {
+		if dt, err := time.ParseInLocation(frontendDatetimeLayout, l.RawTime, tc.Location); err == nil {
 			return &dt, nil
 		} else {
 			return nil, err
@@ -0,0 +1,306 @@
+package model
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// TimeContext holds the values to evaluate relative time expressions such as `now-7d` or `$__from/d`.
+type TimeContext struct {
+	Location       *time.Location
+	Now            time.Time
+	DashboardRange backend.TimeRange
+}
+
+// relativeTimeAnchors are the beginnings of relative time expressions, and the functions returning their base time.
+var relativeTimeAnchors = []struct {
+	prefix string
+	base   func(tc *TimeContext) time.Time
+}{
+	{"now", func(tc *TimeContext) time.Time { return tc.Now }},
+	{"${__from}", func(tc *TimeContext) time.Time { return tc.DashboardRange.From }},
+	{"$__from", func(tc *TimeContext) time.Time { return tc.DashboardRange.From }},
+	{"${__to}", func(tc *TimeContext) time.Time { return tc.DashboardRange.To }},
+	{"$__to", func(tc *TimeContext) time.Time { return tc.DashboardRange.To }},
+}
+
+func isRelativeTime(expr string) bool {
+	for _, anchor := range relativeTimeAnchors {
+		if strings.HasPrefix(expr, anchor.prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRelativeTime evaluates a relative time expression in the location of the context.
+// The expression is an anchor (`now`, `$__from` or `$__to`) followed by offsets like `-7d` or `+1h`
+// and roundings like `/d`. Units are s, m, h, d, w, M and y.
+// When roundUp is true, roundings move the time to the end of the unit instead of the beginning,
+// so that `now/d` means the end of today as an end time.
+func parseRelativeTime(expr string, tc *TimeContext, roundUp bool) (time.Time, error) {
+	var (
+		dt   time.Time
+		rest string
+	)
+	for _, anchor := range relativeTimeAnchors {
+		if strings.HasPrefix(expr, anchor.prefix) {
+			dt, rest = anchor.base(tc).In(tc.Location), expr[len(anchor.prefix):]
+			break
+		}
+	}
+
+	for rest != "" {
+		op := rest[0]
+		rest = rest[1:]
+		switch op {
+		case '+', '-':
+			digits := len(rest) - len(strings.TrimLeft(rest, "0123456789"))
+			if digits == 0 || digits == len(rest) {
+				return time.Time{}, errors.Newf("invalid relative time '%s': offset needs an amount and a unit", expr)
+			}
+			amount, err := strconv.Atoi(rest[:digits])
+			if err != nil {
+				return time.Time{}, errors.Wrapf(err, "invalid relative time '%s'", expr)
+			}
+			if op == '-' {
+				amount = -amount
+			}
+			if dt, err = addTimeUnit(dt, amount, rest[digits]); err != nil {
+				return time.Time{}, errors.Wrapf(err, "invalid relative time '%s'", expr)
+			}
+			rest = rest[digits+1:]
+		case '/':
+			if rest == "" {
+				return time.Time{}, errors.Newf("invalid relative time '%s': rounding needs a unit", expr)
+			}
+			var err error
+			if dt, err = roundTimeUnit(dt, rest[0], roundUp); err != nil {
+				return time.Time{}, errors.Wrapf(err, "invalid relative time '%s'", expr)
+			}
+			rest = rest[1:]
+		default:
+			return time.Time{}, errors.Newf("invalid relative time '%s': unexpected character '%c'", expr, op)
+		}
+	}
+	return dt, nil
+}
+
+func addTimeUnit(dt time.Time, amount int, unit byte) (time.Time, error) {
+	switch unit {
+	case 's':
+		return dt.Add(time.Duration(amount) * time.Second), nil
+	case 'm':
+		return dt.Add(time.Duration(amount) * time.Minute), nil
+	case 'h':
+		return dt.Add(time.Duration(amount) * time.Hour), nil
+	case 'd':
+		return dt.AddDate(0, 0, amount), nil
+	case 'w':
+		return dt.AddDate(0, 0, 7*amount), nil
+	case 'M':
+		return addMonths(dt, amount), nil
+	case 'y':
+		return addMonths(dt, 12*amount), nil
+	default:
+		return time.Time{}, errors.Newf("unknown time unit '%c'", unit)
+	}
+}
+
+// addMonths returns dt moved by the months, clamping the day to the end of the month as Grafana does,
+// so that one month before March 31 is the end of February instead of the beginning of March.
+func addMonths(dt time.Time, months int) time.Time {
+	first := time.Date(dt.Year(), dt.Month()+time.Month(months), 1, dt.Hour(), dt.Minute(), dt.Second(), dt.Nanosecond(), dt.Location())
+	lastDay := time.Date(first.Year(), first.Month()+1, 0, 0, 0, 0, 0, dt.Location()).Day()
+	return time.Date(first.Year(), first.Month(), min(dt.Day(), lastDay), dt.Hour(), dt.Minute(), dt.Second(), dt.Nanosecond(), dt.Location())
+}
+
+// roundTimeUnit returns the beginning of the unit containing dt, or the last second of it when roundUp is true.
+// The last second is the inclusive end in the keys of FIAP, whose times are whole seconds.
+// Weeks begin on Monday.
+func roundTimeUnit(dt time.Time, unit byte, roundUp bool) (time.Time, error) {
+	var (
+		start time.Time
+		next  time.Time
+	)
+	switch unit {
+	case 's':
+		start = time.Date(dt.Year(), dt.Month(), dt.Day(), dt.Hour(), dt.Minute(), dt.Second(), 0, dt.Location())
+		next = start.Add(time.Second)
+	case 'm':
+		start = time.Date(dt.Year(), dt.Month(), dt.Day(), dt.Hour(), dt.Minute(), 0, 0, dt.Location())
+		next = start.Add(time.Minute)
+	case 'h':
+		start = time.Date(dt.Year(), dt.Month(), dt.Day(), dt.Hour(), 0, 0, 0, dt.Location())
+		next = start.Add(time.Hour)
+	case 'd':
+		start = time.Date(dt.Year(), dt.Month(), dt.Day(), 0, 0, 0, 0, dt.Location())
+		next = start.AddDate(0, 0, 1)
+	case 'w':
+		daysFromMonday := (int(dt.Weekday()) + 6) % 7
+		start = time.Date(dt.Year(), dt.Month(), dt.Day()-daysFromMonday, 0, 0, 0, 0, dt.Location())
+		next = start.AddDate(0, 0, 7)
+	case 'M':
+		start = time.Date(dt.Year(), dt.Month(), 1, 0, 0, 0, 0, dt.Location())
+		next = start.AddDate(0, 1, 0)
+	case 'y':
+		start = time.Date(dt.Year(), time.January, 1, 0, 0, 0, 0, dt.Location())
+		next = start.AddDate(1, 0, 0)
+	default:
+		return time.Time{}, errors.Newf("unknown time unit '%c'", unit)
+	}
+	if roundUp {
+		return next.Add(-time.Second), nil
+	}
+	return start, nil
+}
+
+// TimeShift is a parsed time shift like `1w` or `1d12h`, which moves the time range of a query into the past.
+type TimeShift []timeOffset
+
+type timeOffset struct {
+	amount int
+	unit   byte
+}
+
+// ParseTimeShift parses a sequence of amounts and units such as `1w` or `1d12h`.
+// Units are the same as relative time expressions.
+func ParseTimeShift(expr string) (TimeShift, error) {
+	shift := make(TimeShift, 0)
+	rest := expr
+	for rest != "" {
+		digits := len(rest) - len(strings.TrimLeft(rest, "0123456789"))
+		if digits == 0 || digits == len(rest) {
+			return nil, errors.Newf("invalid time shift '%s': each offset needs an amount and a unit", expr)
+		}
+		amount, err := strconv.Atoi(rest[:digits])
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid time shift '%s'", expr)
+		}
+		if _, err := addTimeUnit(time.Time{}, amount, rest[digits]); err != nil {
+			return nil, errors.Wrapf(err, "invalid time shift '%s'", expr)
+		}
+		shift = append(shift, timeOffset{amount: amount, unit: rest[digits]})
+		rest = rest[digits+1:]
+	}
+	return shift, nil
+}
+
+// Back moves the time into the past by the time shift.
+func (s TimeShift) Back(dt time.Time) time.Time {
+	for _, offset := range s {
+		dt, _ = addTimeUnit(dt, -offset.amount, offset.unit)
+	}
+	return dt
+}
+
+// Forward moves the time into the future by the time shift.
+// It reverts Back except for the days clamped to the end of a month by Back,
+// so that March 31 shifted back by `1M` is February 29 and forward again March 29.
+func (s TimeShift) Forward(dt time.Time) time.Time {
+	for _, offset := range s {
+		dt, _ = addTimeUnit(dt, offset.amount, offset.unit)
+	}
+	return dt
+}
+
+// Interval is the width of buckets like `15m` or `1d`, which are aligned to the calendar of the location.
+type Interval struct {
+	amount int
+	unit   byte
+}
+
+// ParseInterval parses an amount and a unit such as `15m` or `1d`.
+// Units are the same as relative time expressions.
+func ParseInterval(expr string) (Interval, error) {
+	digits := len(expr) - len(strings.TrimLeft(expr, "0123456789"))
+	if digits == 0 || digits != len(expr)-1 {
+		return Interval{}, errors.Newf("invalid interval '%s': interval needs an amount and a unit", expr)
+	}
+	amount, err := strconv.Atoi(expr[:digits])
+	if err != nil {
+		return Interval{}, errors.Wrapf(err, "invalid interval '%s'", expr)
+	}
+	if amount <= 0 {
+		return Interval{}, errors.Newf("invalid interval '%s': amount must be positive", expr)
+	}
+	if _, err := addTimeUnit(time.Time{}, amount, expr[digits]); err != nil {
+		return Interval{}, errors.Wrapf(err, "invalid interval '%s'", expr)
+	}
+	return normalizeInterval(Interval{amount: amount, unit: expr[digits]}), nil
+}
+
+// IntervalOf returns the interval of the duration in seconds, which is at least a second.
+func IntervalOf(d time.Duration) Interval {
+	seconds := int(d / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return normalizeInterval(Interval{amount: seconds, unit: 's'})
+}
+
+// Add returns dt advanced by the interval in the location of dt.
+func (i Interval) Add(dt time.Time) time.Time {
+	dt, _ = addTimeUnit(dt, i.amount, i.unit)
+	return dt
+}
+
+// normalizeInterval converts intervals of whole days into days, so that they are aligned to midnight.
+func normalizeInterval(i Interval) Interval {
+	if i.unit == 's' || i.unit == 'm' || i.unit == 'h' {
+		if d := i.duration(); d%(24*time.Hour) == 0 {
+			return Interval{amount: int(d / (24 * time.Hour)), unit: 'd'}
+		}
+	}
+	return i
+}
+
+func (i Interval) duration() time.Duration {
+	switch i.unit {
+	case 's':
+		return time.Duration(i.amount) * time.Second
+	case 'm':
+		return time.Duration(i.amount) * time.Minute
+	default:
+		return time.Duration(i.amount) * time.Hour
+	}
+}
+
+// Truncate returns the beginning of the bucket containing dt in the location of dt.
+// Buckets shorter than a day are counted from midnight, buckets of days from 1970-01-01,
+// weeks from Monday 1970-01-05, and months and years from the beginning of the year 0.
+func (i Interval) Truncate(dt time.Time) time.Time {
+	switch i.unit {
+	case 'd':
+		days := daysSinceEpoch(dt)
+		return time.Date(1970, time.January, 1+days-floorMod(days, i.amount), 0, 0, 0, 0, dt.Location())
+	case 'w':
+		monday, _ := roundTimeUnit(dt, 'w', false)
+		weeks := (daysSinceEpoch(monday) - 4) / 7
+		return monday.AddDate(0, 0, -7*floorMod(weeks, i.amount))
+	case 'M':
+		months := dt.Year()*12 + int(dt.Month()) - 1
+		months -= floorMod(months, i.amount)
+		return time.Date(months/12, time.Month(months%12+1), 1, 0, 0, 0, 0, dt.Location())
+	case 'y':
+		return time.Date(dt.Year()-floorMod(dt.Year(), i.amount), time.January, 1, 0, 0, 0, 0, dt.Location())
+	default:
+		midnight, _ := roundTimeUnit(dt, 'd', false)
+		d := i.duration()
+		return midnight.Add(dt.Sub(midnight) / d * d)
+	}
+}
+
+// daysSinceEpoch returns the number of days from 1970-01-01 to the date of dt in its location.
+func daysSinceEpoch(dt time.Time) int {
+	date := time.Date(dt.Year(), dt.Month(), dt.Day(), 0, 0, 0, 0, time.UTC)
+	return int(date.Unix() / (24 * 60 * 60))
+}
+
+func floorMod(a int, b int) int {
+	return ((a % b) + b) % b
+}
@@ -0,0 +1,73 @@
+package model
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+const DefaultInitialBackoff = 200 * time.Millisecond
+const DefaultMaxBackoff = 5 * time.Second
+
+// RetryPolicy retries the requests failing transiently, that is network errors such as dropped connections
+// and server errors of 500, 502, 503 and 504.
+// Requests are never retried on client errors of HTTP, nor on the errors of the certificates, TLS and the URL.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts of a request including the first one. 0 or 1 disables retries.
+	MaxAttempts int `json:"max_attempts,omitempty"`
+	// InitialBackoff is the milliseconds to wait before the first retry, doubled on each retry. 0 means DefaultInitialBackoff.
+	InitialBackoff int `json:"initial_backoff_ms,omitempty"`
+	// MaxBackoff is the upper bound of the milliseconds to wait. 0 means DefaultMaxBackoff.
+	MaxBackoff int `json:"max_backoff_ms,omitempty"`
+	// Jitter is the ratio from 0 to 1 of the backoff which is randomly reduced, so that clients do not retry at once.
+	Jitter float64 `json:"jitter,omitempty"`
+	// FiapErrorTypes are the types of FIAP errors which are retried.
+	FiapErrorTypes []string `json:"fiap_error_types,omitempty"`
+}
+
+func (p *RetryPolicy) Validate() error {
+	if p.MaxAttempts < 0 {
+		return errors.Newf("max attempts must not be negative but %d", p.MaxAttempts)
+	}
+	if p.InitialBackoff < 0 || p.MaxBackoff < 0 {
+		return errors.Newf("backoff must not be negative but %d, %d", p.InitialBackoff, p.MaxBackoff)
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return errors.Newf("jitter must be from 0 to 1 but %v", p.Jitter)
+	}
+	return nil
+}
+
+// Enabled returns whether the requests are retried.
+func (p *RetryPolicy) Enabled() bool {
+	return p.MaxAttempts > 1
+}
+
+// Backoff returns the duration to wait before the retry following the attempt, which starts from 1.
+// random is a number from 0 to 1 which chooses the jitter.
+func (p *RetryPolicy) Backoff(attempt int, random float64) time.Duration {
+	initial, max := DefaultInitialBackoff, DefaultMaxBackoff
+	if p.InitialBackoff > 0 {
+		initial = time.Duration(p.InitialBackoff) * time.Millisecond
+	}
+	if p.MaxBackoff > 0 {
+		max = time.Duration(p.MaxBackoff) * time.Millisecond
+	}
+	backoff := float64(initial) * math.Pow(2, float64(attempt-1))
+	if backoff > float64(max) {
+		backoff = float64(max)
+	}
+	return time.Duration(backoff * (1 - p.Jitter*random))
+}
+
+// RetriesFiapError returns whether the FIAP error of the type is retried.
+func (p *RetryPolicy) RetriesFiapError(errorType string) bool {
+	for _, retried := range p.FiapErrorTypes {
+		if strings.EqualFold(retried, errorType) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,22 +1,231 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
 
 type FiapDatasourceSettings struct {
 	Url            string `json:"url"`
 	ServerTimezone string `json:"server_timezone"`
+	// StandbyUrls are the endpoints requested in this order when Url fails.
+	StandbyUrls []string `json:"standby_urls,omitempty"`
+	// FailbackInterval is the seconds between the probes of the failed endpoints. 0 means DefaultFailbackInterval.
+	FailbackInterval int `json:"failback_interval,omitempty"`
+	// AcceptableSize is the maximum number of values in one response.
+	// When it is set, the data is fetched page by page with the cursor of FIAP.
+	AcceptableSize uint `json:"acceptable_size,omitempty"`
+	// MaxRecords is the maximum number of values fetched by one query, over all the points, pages and expanded point sets.
+	// The rest is dropped with a notice on the frames of the points cut off. 0 means no limit.
+	MaxRecords int `json:"max_records,omitempty"`
+	// DefaultValueMapping converts the values of points which are not numbers, unless the point has its own mapping.
+	DefaultValueMapping *ValueMapping `json:"default_value_mapping,omitempty"`
+	// PointUnits is the registry of the units of points, keyed by point IDs.
+	PointUnits map[string]PointUnit `json:"point_units,omitempty"`
+	// AuthType is the authentication of the requests to the FIAP server.
+	AuthType AuthType `json:"auth_type,omitempty"`
+	// BasicAuthUser is the user name of the basic authentication.
+	BasicAuthUser string `json:"basic_auth_user,omitempty"`
+	// TLSServerName overrides the server name verified against the certificate of the server.
+	TLSServerName string `json:"tls_server_name,omitempty"`
+	// TLSSkipVerify skips the verification of the certificate of the server. It is meant only for test environments.
+	TLSSkipVerify bool `json:"tls_skip_verify,omitempty"`
+	// ProxyURL is the URL of the HTTP proxy to the FIAP server. The proxy of the environment is used when it is empty.
+	ProxyURL string `json:"proxy_url,omitempty"`
+	// ProxyUser is the user name of the proxy authentication.
+	ProxyUser string `json:"proxy_user,omitempty"`
+	// HeaderNames are the names of the headers added to every request to the FIAP server, keyed by their indexes.
+	// They are the keys httpHeaderName1, httpHeaderName2 and so on, as the other datasources of Grafana,
+	// and the values of the headers are the secure settings of the same indexes.
+	HeaderNames map[string]string `json:"-"`
+	// ConnectTimeout is the seconds to establish the connection to the FIAP server. 0 means DefaultConnectTimeout.
+	ConnectTimeout int `json:"connect_timeout,omitempty"`
+	// ReadTimeout is the seconds to wait for the response headers after the request is sent. 0 means DefaultReadTimeout.
+	// The transfer of the response body is bounded by the deadline of the query or the health check instead.
+	ReadTimeout int `json:"read_timeout,omitempty"`
+	// HealthProbePointID is the point whose latest value is fetched by the health check.
+	// When it is empty, the health check sends a query of a point which does not need to exist.
+	HealthProbePointID string `json:"health_probe_point_id,omitempty"`
+	// Retry is the retry policy of the requests fetching point data.
+	Retry RetryPolicy `json:"retry,omitempty"`
+
+	// Secure holds the values of the secure settings, which are never marshalled.
+	Secure FiapSecureSettings `json:"-"`
+}
+
+// FiapSecureSettings are the settings stored encrypted by Grafana.
+type FiapSecureSettings struct {
+	BasicAuthPassword string
+	BearerToken       string
+	// TLSCACert is the PEM bundle of the CA certificates trusted in addition to the system ones.
+	TLSCACert string
+	// TLSClientCert and TLSClientKey are the PEM client certificate and its key of mutual TLS.
+	TLSClientCert string
+	TLSClientKey  string
+	// ProxyPassword is the password of the proxy authentication.
+	ProxyPassword string
+	// HeaderValues are the values of the custom headers keyed by their indexes. See HeaderNames.
+	HeaderValues map[string]string
+}
+
+const headerNamePrefix = "httpHeaderName"
+const headerValuePrefix = "httpHeaderValue"
+
+// UnmarshalJSON unmarshals the settings, collecting the names of the custom headers from the keys of their indexes.
+func (s *FiapDatasourceSettings) UnmarshalJSON(b []byte) error {
+	type settings FiapDatasourceSettings
+	if err := json.Unmarshal(b, (*settings)(s)); err != nil {
+		return err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return err
+	}
+	s.HeaderNames = nil
+	for key, field := range fields {
+		index, ok := strings.CutPrefix(key, headerNamePrefix)
+		if !ok {
+			continue
+		}
+		var name string
+		if err := json.Unmarshal(field, &name); err != nil {
+			return errors.Wrapf(err, "custom header name '%s'", key)
+		}
+		if s.HeaderNames == nil {
+			s.HeaderNames = make(map[string]string)
+		}
+		s.HeaderNames[index] = name
+	}
+	return nil
+}
+
+// CustomHeaders returns the values of the custom headers keyed by their names.
+// The header names left empty are skipped.
+func (s *FiapDatasourceSettings) CustomHeaders() map[string]string {
+	headers := make(map[string]string, len(s.HeaderNames))
+	for index, name := range s.HeaderNames {
+		if name != "" {
+			headers[name] = s.Secure.HeaderValues[index]
+		}
+	}
+	return headers
+}
+
+// LoadSecureSettings returns the secure settings of the decrypted values keyed by their names.
+func LoadSecureSettings(decrypted map[string]string) FiapSecureSettings {
+	var headerValues map[string]string
+	for key, value := range decrypted {
+		if index, ok := strings.CutPrefix(key, headerValuePrefix); ok {
+			if headerValues == nil {
+				headerValues = make(map[string]string)
+			}
+			headerValues[index] = value
+		}
+	}
+	return FiapSecureSettings{
+		BasicAuthPassword: decrypted["basic_auth_password"],
+		BearerToken:       decrypted["bearer_token"],
+		TLSCACert:         decrypted["tls_ca_cert"],
+		TLSClientCert:     decrypted["tls_client_cert"],
+		TLSClientKey:      decrypted["tls_client_key"],
+		ProxyPassword:     decrypted["proxy_password"],
+		HeaderValues:      headerValues,
+	}
+}
+
+const DefaultFailbackInterval = 30 * time.Second
+
+// Endpoints returns the URLs of the FIAP servers in the order of the failover, skipping the duplicates.
+func (s *FiapDatasourceSettings) Endpoints() []string {
+	endpoints := []string{s.Url}
+	for _, url := range s.StandbyUrls {
+		if !slices.Contains(endpoints, url) {
+			endpoints = append(endpoints, url)
+		}
+	}
+	return endpoints
+}
+
+// GetFailbackInterval returns the interval of the probes of the failed endpoints.
+func (s *FiapDatasourceSettings) GetFailbackInterval() (time.Duration, error) {
+	if s.FailbackInterval < 0 {
+		return 0, errors.Newf("failback interval must not be negative but %d", s.FailbackInterval)
+	}
+	if s.FailbackInterval == 0 {
+		return DefaultFailbackInterval, nil
+	}
+	return time.Duration(s.FailbackInterval) * time.Second, nil
+}
+
+const DefaultConnectTimeout = 10 * time.Second
+const DefaultReadTimeout = 60 * time.Second
+
+// Timeouts returns the connect timeout and the read timeout, or their defaults when they are not set.
+func (s *FiapDatasourceSettings) Timeouts() (connect time.Duration, read time.Duration, err error) {
+	if s.ConnectTimeout < 0 {
+		return 0, 0, errors.Newf("connect timeout must not be negative but %d", s.ConnectTimeout)
+	}
+	if s.ReadTimeout < 0 {
+		return 0, 0, errors.Newf("read timeout must not be negative but %d", s.ReadTimeout)
+	}
+	connect, read = DefaultConnectTimeout, DefaultReadTimeout
+	if s.ConnectTimeout > 0 {
+		connect = time.Duration(s.ConnectTimeout) * time.Second
+	}
+	if s.ReadTimeout > 0 {
+		read = time.Duration(s.ReadTimeout) * time.Second
+	}
+	return connect, read, nil
+}
+
+type AuthType string
+
+const NoAuth AuthType = ""
+const BasicAuth AuthType = "basic"
+const BearerAuth AuthType = "bearer"
+
+// ValidateAuth returns an error when the authentication lacks its credentials.
+func (s *FiapDatasourceSettings) ValidateAuth() error {
+	switch s.AuthType {
+	case NoAuth:
+	case BasicAuth:
+		if s.BasicAuthUser == "" {
+			return errors.New("basic authentication needs a user name")
+		}
+	case BearerAuth:
+		if s.Secure.BearerToken == "" {
+			return errors.New("bearer authentication needs a token")
+		}
+	default:
+		return errors.Newf("unknown auth type '%s'", s.AuthType)
+	}
+	return nil
 }
 
 const serverTimezoneLayout = "-07:00"
 
+// GetLocation returns the location of the server timezone.
+// See LoadLocation for the format of the timezone.
 func (s *FiapDatasourceSettings) GetLocation() (*time.Location, error) {
 	if s.ServerTimezone == "" {
 		return time.UTC, nil
+	}
+	return LoadLocation(s.ServerTimezone)
+}
+
+// LoadLocation returns the location of the timezone.
+// The timezone is either an offset like `+09:00`, which is a fixed offset location,
+// or an IANA timezone name like `Europe/Berlin`, which follows daylight saving time.
+func LoadLocation(timezone string) (*time.Location, error) {
+	if dt, err := time.Parse(serverTimezoneLayout, timezone); err == nil {
+		return dt.Location(), nil
+	} else if loc, err := time.LoadLocation(timezone); err == nil {
+		return loc, nil
 	} else {
-		if dt, err := time.Parse(serverTimezoneLayout, s.ServerTimezone); err == nil {
-			return dt.Location(), nil
-		} else {
-			return nil, err
-		}
+		return nil, errors.Wrapf(err, "timezone '%s' is neither an offset like +09:00 nor an IANA timezone name", timezone)
 	}
 }
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"github.com/cockroachdb/errors"
+)
+
+// PointUnit is the unit of the values of a point, and the unit shown in panels.
+// Units are either symbols like `kW` or unit IDs of Grafana like `kwatt`.
+// Units unknown to the datasource are passed to Grafana as they are, but they can not be converted.
+type PointUnit struct {
+	Unit string `json:"unit,omitempty"`
+	// DisplayUnit converts the values into the unit when it is set.
+	DisplayUnit string `json:"display_unit,omitempty"`
+}
+
+// Merge returns the unit where the fields of p override the fields of base.
+func (p PointUnit) Merge(base PointUnit) PointUnit {
+	if p.Unit != "" {
+		base.Unit = p.Unit
+	}
+	if p.DisplayUnit != "" {
+		base.DisplayUnit = p.DisplayUnit
+	}
+	return base
+}
+
+func (p *PointUnit) Validate() error {
+	if p.DisplayUnit == "" {
+		return nil
+	}
+	_, err := p.Converter()
+	return err
+}
+
+// GrafanaUnit returns the unit ID of Grafana shown in panels.
+func (p *PointUnit) GrafanaUnit() string {
+	unit := p.Unit
+	if p.DisplayUnit != "" {
+		unit = p.DisplayUnit
+	}
+	if known, ok := LookupUnit(unit); ok {
+		return known.GrafanaUnit
+	}
+	return unit
+}
+
+// Converter returns the function converting values from Unit into DisplayUnit.
+// Values are not converted when either of them is empty.
+func (p *PointUnit) Converter() (func(float64) float64, error) {
+	if p.Unit == "" || p.DisplayUnit == "" || p.DisplayUnit == p.Unit {
+		return func(v float64) float64 { return v }, nil
+	}
+	from, ok := LookupUnit(p.Unit)
+	if !ok {
+		return nil, errors.Newf("unit '%s' can not be converted", p.Unit)
+	}
+	to, ok := LookupUnit(p.DisplayUnit)
+	if !ok {
+		return nil, errors.Newf("unit '%s' can not be converted", p.DisplayUnit)
+	}
+	if from.quantity != to.quantity {
+		return nil, errors.Newf("unit '%s' can not be converted into '%s'", p.Unit, p.DisplayUnit)
+	}
+	return func(v float64) float64 {
+		return (v*from.scale + from.offset - to.offset) / to.scale
+	}, nil
+}
+
+// Unit is a unit known by the datasource. A value v of the unit is v*scale+offset in the base unit of the quantity.
+type Unit struct {
+	Symbol      string
+	GrafanaUnit string
+	quantity    string
+	scale       float64
+	offset      float64
+}
+
+var knownUnits = []Unit{
+	{Symbol: "W", GrafanaUnit: "watt", quantity: "power", scale: 1},
+	{Symbol: "kW", GrafanaUnit: "kwatt", quantity: "power", scale: 1000},
+	{Symbol: "Wh", GrafanaUnit: "watth", quantity: "energy", scale: 1},
+	{Symbol: "kWh", GrafanaUnit: "kwatth", quantity: "energy", scale: 1000},
+	{Symbol: "°C", GrafanaUnit: "celsius", quantity: "temperature", scale: 1},
+	{Symbol: "°F", GrafanaUnit: "fahrenheit", quantity: "temperature", scale: 5.0 / 9, offset: -160.0 / 9},
+	{Symbol: "Pa", GrafanaUnit: "pressurepa", quantity: "pressure", scale: 1},
+	{Symbol: "kPa", GrafanaUnit: "pressurekpa", quantity: "pressure", scale: 1000},
+}
+
+// LookupUnit returns the known unit of the symbol or the unit ID of Grafana.
+func LookupUnit(name string) (*Unit, bool) {
+	for i := range knownUnits {
+		if knownUnits[i].Symbol == name || knownUnits[i].GrafanaUnit == name {
+			return &knownUnits[i], true
+		}
+	}
+	return nil, false
+}
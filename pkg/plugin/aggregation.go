@@ -0,0 +1,192 @@
+package plugin
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/cockroachdb/errors"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// aggregateFrames replaces the rows of each frame with one row per bucket of the interval.
+// Buckets are aligned in the location, and buckets without rows are left out.
+func aggregateFrames(frames data.Frames, aggregation dsmodel.AggregationType, interval dsmodel.Interval, location *time.Location) error {
+	aggregateErrors := make([]error, 0)
+	for _, frame := range frames {
+		if err := aggregateFrame(frame, aggregation, interval, location); err != nil {
+			aggregateErrors = append(aggregateErrors, errors.Wrapf(err, "aggregate frame '%s'", frame.Name))
+		}
+	}
+	return errors.Join(aggregateErrors...)
+}
+
+// timeBucket is the rows of a frame in a bucket, sorted by time.
+type timeBucket struct {
+	start time.Time
+	rows  []int
+}
+
+func aggregateFrame(frame *data.Frame, aggregation dsmodel.AggregationType, interval dsmodel.Interval, location *time.Location) error {
+	timeIndex := -1
+	for i, field := range frame.Fields {
+		if field.Type() == data.FieldTypeTime {
+			timeIndex = i
+			break
+		}
+	}
+	if timeIndex < 0 {
+		return errors.New("frame has no time field")
+	}
+	buckets := bucketRows(frame.Fields[timeIndex], interval, location)
+
+	times := make([]time.Time, len(buckets))
+	for i, bucket := range buckets {
+		times[i] = bucket.start
+	}
+	timeField := data.NewField(frame.Fields[timeIndex].Name, frame.Fields[timeIndex].Labels, times)
+	timeField.Config = frame.Fields[timeIndex].Config
+	fields := []*data.Field{timeField}
+
+	for i, field := range frame.Fields {
+		if i == timeIndex {
+			continue
+		}
+		aggregated, err := aggregateField(field, aggregation, buckets)
+		if err != nil {
+			return err
+		}
+		if aggregated != nil {
+			fields = append(fields, aggregated)
+		}
+	}
+	frame.Fields = fields
+	return nil
+}
+
+// bucketRows groups the rows by the bucket containing their time, in order of time.
+func bucketRows(timeField *data.Field, interval dsmodel.Interval, location *time.Location) []*timeBucket {
+	rows := make([]int, timeField.Len())
+	for i := range rows {
+		rows[i] = i
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return timeField.At(rows[i]).(time.Time).Before(timeField.At(rows[j]).(time.Time))
+	})
+
+	buckets := make([]*timeBucket, 0)
+	for _, row := range rows {
+		start := interval.Truncate(timeField.At(row).(time.Time).In(location))
+		if len(buckets) == 0 || !buckets[len(buckets)-1].start.Equal(start) {
+			buckets = append(buckets, &timeBucket{start: start})
+		}
+		buckets[len(buckets)-1].rows = append(buckets[len(buckets)-1].rows, row)
+	}
+	return buckets
+}
+
+// aggregateField returns a field holding the aggregated value of each bucket.
+// first and last keep the type of the field, count returns integers, and the others return nullable numbers.
+// It returns nil for the fields of rejected values, which are not numbers.
+// Fields without values, such as the ones of points without samples, are aggregated into empty buckets.
+func aggregateField(field *data.Field, aggregation dsmodel.AggregationType, buckets []*timeBucket) (*data.Field, error) {
+	var aggregated *data.Field
+	switch aggregation {
+	case dsmodel.AggregationFirst, dsmodel.AggregationLast:
+		aggregated = data.NewFieldFromFieldType(field.Type(), len(buckets))
+		for i, bucket := range buckets {
+			if row, ok := edgeRowOf(field, bucket.rows, aggregation == dsmodel.AggregationLast); ok {
+				aggregated.Set(i, field.CopyAt(row))
+			}
+		}
+	case dsmodel.AggregationCount:
+		counts := make([]int64, len(buckets))
+		for i, bucket := range buckets {
+			for _, row := range bucket.rows {
+				if !field.NilAt(row) {
+					counts[i]++
+				}
+			}
+		}
+		aggregated = data.NewField(field.Name, nil, counts)
+	default:
+		if !field.Type().Numeric() && strings.HasSuffix(field.Name, rejectedFieldSuffix) {
+			return nil, nil
+		}
+		if !field.Type().Numeric() && !isEmptyField(field) {
+			return nil, errors.Newf("aggregation '%s' needs numbers, but field '%s' is %s", aggregation, field.Name, field.Type().ItemTypeString())
+		}
+		values := make([]*float64, len(buckets))
+		for i, bucket := range buckets {
+			values[i] = aggregateNumbers(field, aggregation, bucket.rows)
+		}
+		aggregated = data.NewField(field.Name, nil, values)
+	}
+	aggregated.Name, aggregated.Labels, aggregated.Config = field.Name, field.Labels, field.Config
+	return aggregated, nil
+}
+
+// isEmptyField reports whether the field has no values, which is true for fields without rows or whose values are all null.
+func isEmptyField(field *data.Field) bool {
+	for i := 0; i < field.Len(); i++ {
+		if !field.NilAt(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// edgeRowOf returns the first row whose value is not null, or the last one when last is true.
+// It returns the edge row regardless of the value when all values are null.
+func edgeRowOf(field *data.Field, rows []int, last bool) (int, bool) {
+	if len(rows) == 0 {
+		return 0, false
+	}
+	for i := range rows {
+		row := rows[i]
+		if last {
+			row = rows[len(rows)-1-i]
+		}
+		if !field.NilAt(row) {
+			return row, true
+		}
+	}
+	if last {
+		return rows[len(rows)-1], true
+	}
+	return rows[0], true
+}
+
+// aggregateNumbers returns the aggregated value of the numbers in the rows, or nil when all of them are null.
+func aggregateNumbers(field *data.Field, aggregation dsmodel.AggregationType, rows []int) *float64 {
+	var (
+		result float64
+		count  int
+	)
+	for _, row := range rows {
+		value, err := field.NullableFloatAt(row)
+		if err != nil || value == nil {
+			continue
+		}
+		switch {
+		case count == 0:
+			result = *value
+		case aggregation == dsmodel.AggregationMin && *value < result:
+			result = *value
+		case aggregation == dsmodel.AggregationMax && *value > result:
+			result = *value
+		case aggregation == dsmodel.AggregationAvg || aggregation == dsmodel.AggregationSum:
+			result += *value
+		}
+		count++
+	}
+	if count == 0 {
+		return nil
+	}
+	if aggregation == dsmodel.AggregationAvg {
+		result /= float64(count)
+	}
+	return &result
+}
@@ -0,0 +1,179 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestAggregateFrames(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	newFrame := func() *data.Frame {
+		return data.NewFrame("A:id_a",
+			data.NewField("time", nil, []time.Time{
+				time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC),
+				time.Date(2024, 5, 1, 16, 0, 0, 0, time.UTC),
+				time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC),
+				time.Date(2024, 5, 2, 1, 0, 0, 0, time.UTC),
+			}),
+			data.NewField("id_a", data.Labels{"path": "set"}, []*float64{pointerOf(1.0), pointerOf(4.0), nil, pointerOf(2.0)}).
+				SetConfig(&data.FieldConfig{DisplayNameFromDS: "alias"}),
+			data.NewField("id_a"+rejectedFieldSuffix, nil, []*string{nil, nil, pointerOf("error"), nil}),
+		)
+	}
+	daily, err := dsmodel.ParseInterval("1d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Run("Normal", func(t *testing.T) {
+		cases := []struct {
+			aggregation dsmodel.AggregationType
+			expected    []interface{}
+		}{
+			{dsmodel.AggregationAvg, []interface{}{pointerOf(1.0), pointerOf(3.0)}},
+			{dsmodel.AggregationMin, []interface{}{pointerOf(1.0), pointerOf(2.0)}},
+			{dsmodel.AggregationMax, []interface{}{pointerOf(1.0), pointerOf(4.0)}},
+			{dsmodel.AggregationSum, []interface{}{pointerOf(1.0), pointerOf(6.0)}},
+			{dsmodel.AggregationCount, []interface{}{int64(1), int64(2)}},
+			{dsmodel.AggregationFirst, []interface{}{pointerOf(1.0), pointerOf(4.0)}},
+			{dsmodel.AggregationLast, []interface{}{pointerOf(1.0), pointerOf(2.0)}},
+		}
+		for _, c := range cases {
+			t.Run(string(c.aggregation), func(t *testing.T) {
+				frame := newFrame()
+				if err := aggregateFrames(data.Frames{frame}, c.aggregation, daily, jst); err != nil {
+					t.Fatal(err)
+				}
+				// buckets begin at the midnight of JST.
+				expectedTimes := []time.Time{
+					time.Date(2024, 4, 30, 15, 0, 0, 0, time.UTC),
+					time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC),
+				}
+				if frame.Rows() != len(expectedTimes) {
+					t.Fatalf("expected rows are %d but %d", len(expectedTimes), frame.Rows())
+				}
+				for i, expected := range expectedTimes {
+					if actual := frame.Fields[0].At(i).(time.Time); !actual.Equal(expected) {
+						t.Errorf("expected time[%d] is %s but %s", i, expected, actual)
+					}
+				}
+				valueField := frame.Fields[1]
+				for i, expected := range c.expected {
+					switch expected := expected.(type) {
+					case *float64:
+						if actual := valueField.At(i).(*float64); actual == nil || *actual != *expected {
+							t.Errorf("expected value[%d] is %v but %v", i, *expected, actual)
+						}
+					case int64:
+						if actual := valueField.At(i).(int64); actual != expected {
+							t.Errorf("expected value[%d] is %v but %v", i, expected, actual)
+						}
+					}
+				}
+				if valueField.Labels["path"] != "set" || valueField.Config == nil || valueField.Config.DisplayNameFromDS != "alias" {
+					t.Errorf("expected labels and config are kept but %v, %v", valueField.Labels, valueField.Config)
+				}
+			})
+		}
+		t.Run("RejectedValues", func(t *testing.T) {
+			frame := newFrame()
+			if err := aggregateFrames(data.Frames{frame}, dsmodel.AggregationAvg, daily, jst); err != nil {
+				t.Fatal(err)
+			}
+			if len(frame.Fields) != 2 {
+				t.Errorf("expected rejected values are dropped by avg but %d fields", len(frame.Fields))
+			}
+			frame = newFrame()
+			if err := aggregateFrames(data.Frames{frame}, dsmodel.AggregationLast, daily, jst); err != nil {
+				t.Fatal(err)
+			}
+			if actual := frame.Fields[2].At(1).(*string); actual == nil || *actual != "error" {
+				t.Errorf("expected last rejected value is %s but %v", "error", actual)
+			}
+		})
+		t.Run("Strings", func(t *testing.T) {
+			frame := data.NewFrame("A:id_a",
+				data.NewField("time", nil, []time.Time{
+					time.Date(2024, 5, 1, 0, 10, 0, 0, time.UTC),
+					time.Date(2024, 5, 1, 0, 20, 0, 0, time.UTC),
+				}),
+				data.NewField("id_a", nil, []string{"on", "off"}),
+			)
+			if err := aggregateFrames(data.Frames{frame}, dsmodel.AggregationFirst, dsmodel.IntervalOf(time.Hour), time.UTC); err != nil {
+				t.Fatal(err)
+			}
+			if frame.Rows() != 1 || frame.Fields[1].At(0).(string) != "on" {
+				t.Errorf("expected a row of %s but %v", "on", frame.Fields[1])
+			}
+		})
+		t.Run("EmptyFields", func(t *testing.T) {
+			// points without samples have string fields, which are aggregated into empty buckets.
+			empty := data.NewFrame("A:id_empty",
+				data.NewField("time", nil, []time.Time{}),
+				data.NewField("id_empty", nil, []string{}),
+			)
+			null := data.NewFrame("A:id_null",
+				data.NewField("time", nil, []time.Time{time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}),
+				data.NewField("id_null", nil, []*string{nil}),
+			)
+			if err := aggregateFrames(data.Frames{empty, null}, dsmodel.AggregationAvg, daily, jst); err != nil {
+				t.Fatal(err)
+			}
+			if empty.Rows() != 0 {
+				t.Errorf("expected rows are %d but %d", 0, empty.Rows())
+			}
+			if null.Rows() != 1 || null.Fields[1].At(0).(*float64) != nil {
+				t.Errorf("expected a row of null but %v", null.Fields[1])
+			}
+		})
+	})
+	t.Run("Error", func(t *testing.T) {
+		frame := data.NewFrame("A:id_a",
+			data.NewField("time", nil, []time.Time{time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)}),
+			data.NewField("id_a", nil, []string{"on"}),
+		)
+		if err := aggregateFrames(data.Frames{frame}, dsmodel.AggregationAvg, daily, time.UTC); err == nil {
+			t.Errorf("expected error is %s but nil", "needs numbers")
+		} else if !strings.Contains(err.Error(), "needs numbers") {
+			t.Errorf("expected error is %s but %s", "needs numbers", err.Error())
+		}
+	})
+}
+
+func TestIntervalTruncate(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	dt := time.Date(2024, 5, 15, 13, 47, 12, 0, jst)
+	cases := []struct {
+		interval string
+		expected time.Time
+	}{
+		{"15m", time.Date(2024, 5, 15, 13, 45, 0, 0, jst)},
+		{"6h", time.Date(2024, 5, 15, 12, 0, 0, 0, jst)},
+		{"24h", time.Date(2024, 5, 15, 0, 0, 0, 0, jst)},
+		{"1d", time.Date(2024, 5, 15, 0, 0, 0, 0, jst)},
+		{"1w", time.Date(2024, 5, 13, 0, 0, 0, 0, jst)},
+		{"1M", time.Date(2024, 5, 1, 0, 0, 0, 0, jst)},
+		{"3M", time.Date(2024, 4, 1, 0, 0, 0, 0, jst)},
+		{"1y", time.Date(2024, 1, 1, 0, 0, 0, 0, jst)},
+	}
+	for _, c := range cases {
+		t.Run(c.interval, func(t *testing.T) {
+			interval, err := dsmodel.ParseInterval(c.interval)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := interval.Truncate(dt); !actual.Equal(c.expected) {
+				t.Errorf("expected %s but %s", c.expected, actual)
+			}
+		})
+	}
+	for _, invalid := range []string{"", "m", "0m", "1x", "1d2h"} {
+		if _, err := dsmodel.ParseInterval(invalid); err == nil {
+			t.Errorf("expected error of interval '%s' but nil", invalid)
+		}
+	}
+}
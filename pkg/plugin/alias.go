@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// aliasTokenPattern matches the tokens of alias templates such as `{{point_id}}` and `{{segment -1}}`.
+var aliasTokenPattern = regexp.MustCompile(`\{\{\s*([^{}]*?)\s*\}\}`)
+
+// aliasTemplate renders display names of points by the template of dsmodel.QueryOptions.
+type aliasTemplate struct {
+	template string
+	// pattern is matched against the point ID for capture groups, or nil when it is not given.
+	pattern *regexp.Regexp
+}
+
+func newAliasTemplate(template string, pattern string) (*aliasTemplate, error) {
+	if template == "" {
+		return nil, nil
+	}
+	alias := &aliasTemplate{template: template}
+	if pattern != "" {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		alias.pattern = compiled
+	}
+	return alias, nil
+}
+
+// render returns the display name of the point. Unknown tokens are left as they are,
+// and tokens referring to missing segments, labels or capture groups become empty.
+func (a *aliasTemplate) render(refID string, pointID string, labels data.Labels) string {
+	var captures []string
+	if a.pattern != nil {
+		captures = a.pattern.FindStringSubmatch(pointID)
+	}
+	return aliasTokenPattern.ReplaceAllStringFunc(a.template, func(matched string) string {
+		args := strings.Fields(aliasTokenPattern.FindStringSubmatch(matched)[1])
+		if len(args) == 0 {
+			return matched
+		}
+		switch {
+		case args[0] == "point_id" && len(args) == 1:
+			return pointID
+		case args[0] == "ref_id" && len(args) == 1:
+			return refID
+		case args[0] == "segment" && len(args) == 2:
+			index, err := strconv.Atoi(args[1])
+			if err != nil {
+				return matched
+			}
+			return pointIDSegment(pointID, index)
+		case args[0] == "label" && len(args) == 2:
+			return labels[args[1]]
+		case strings.HasPrefix(args[0], "$") && len(args) == 1:
+			return a.capture(captures, args[0][1:])
+		default:
+			return matched
+		}
+	})
+}
+
+// capture returns the capture group of the number or the name.
+func (a *aliasTemplate) capture(captures []string, group string) string {
+	if captures == nil {
+		return ""
+	}
+	index, err := strconv.Atoi(group)
+	if err != nil {
+		index = a.pattern.SubexpIndex(group)
+	}
+	if index < 0 || index >= len(captures) {
+		return ""
+	}
+	return captures[index]
+}
+
+// pointIDSegment returns the segment of the point ID split by slashes, ignoring empty segments.
+// A negative index counts from the end, so that -1 is the last segment.
+func pointIDSegment(pointID string, index int) string {
+	segments := strings.FieldsFunc(pointID, func(r rune) bool {
+		return r == '/'
+	})
+	if index < 0 {
+		index += len(segments)
+	}
+	if index < 0 || index >= len(segments) {
+		return ""
+	}
+	return segments[index]
+}
+
+// setDisplayName sets the display name of the field given by the datasource.
+func setDisplayName(field *data.Field, name string) {
+	if field.Config == nil {
+		field.Config = &data.FieldConfig{}
+	}
+	field.Config.DisplayNameFromDS = name
+}
@@ -0,0 +1,81 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	fiapmodel "github.com/SIOS-Technology-Inc/go-fiap-client/pkg/fiap/model"
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestAliasTemplateRender(t *testing.T) {
+	pointID := "http://example.com/building1/floor2/temp"
+	labels := data.Labels{"path": "building1 > floor2"}
+	cases := []struct {
+		name     string
+		template string
+		pattern  string
+		expected string
+	}{
+		{"PointID", "{{point_id}}", "", pointID},
+		{"RefID", "{{ ref_id }}: {{segment -1}}", "", "A: temp"},
+		{"Segments", "{{segment 2}}/{{segment -2}}", "", "building1/floor2"},
+		{"SegmentOutOfRange", "[{{segment 10}}]", "", "[]"},
+		{"Label", "{{label path}}", "", "building1 > floor2"},
+		{"MissingLabel", "[{{label unit}}]", "", "[]"},
+		{"NumberedCapture", "{{$1}}-{{$2}}", `building(\d+)/floor(\d+)`, "1-2"},
+		{"NamedCapture", "floor {{$floor}}", `floor(?P<floor>\d+)`, "floor 2"},
+		{"NotMatched", "[{{$1}}]", `room(\d+)`, "[]"},
+		{"UnknownToken", "{{unknown}} {{segment x}}", "", "{{unknown}} {{segment x}}"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			alias, err := newAliasTemplate(c.template, c.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if actual := alias.render("A", pointID, labels); actual != c.expected {
+				t.Errorf("expected %s but %s", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestFetchWithDateRangeAliasTemplate(t *testing.T) {
+	query := &backend.DataQuery{
+		RefID: "A",
+	}
+	fromTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	toTime := time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC)
+	values := []fiapmodel.Value{{Time: fromTime, Value: "1"}}
+	cli, _ := newDateRangeClient(map[string][]fiapmodel.Value{
+		"http://example.com/floor1/temp": values,
+		"http://example.com/floor2/temp": values,
+	}, &dsmodel.FiapDatasourceSettings{})
+	options := &dsmodel.QueryOptions{AliasTemplate: "{{segment -2}} {{segment -1}}"}
+
+	t.Run("Normal", func(t *testing.T) {
+		resp := &backend.DataResponse{}
+		pointIDs := []dsmodel.PointID{{Value: "http://example.com/floor1/temp"}, {Value: "http://example.com/floor2/temp", Alias: "second"}}
+		if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, pointIDs, query, options); err != nil {
+			t.Fatal(err)
+		}
+		// the alias of the point overrides the template.
+		for i, expected := range []string{"floor1 temp", "second"} {
+			if config := resp.Frames[i].Fields[1].Config; config == nil || config.DisplayNameFromDS != expected {
+				t.Errorf("expected display name of frame[%d] is %s but %v", i, expected, config)
+			}
+		}
+	})
+	t.Run("InvalidPattern", func(t *testing.T) {
+		resp := &backend.DataResponse{}
+		pointIDs := []dsmodel.PointID{{Value: "http://example.com/floor1/temp"}}
+		if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{AliasTemplate: "{{$1}}", AliasPattern: "("}); err == nil {
+			t.Error("expected error of the alias pattern but nil")
+		}
+	})
+}
@@ -0,0 +1,27 @@
+package plugin
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+)
+
+// authorizeFn returns the function setting the Authorization header of the authentication in the settings,
+// or nil when the requests are not authenticated.
+func authorizeFn(settings *dsmodel.FiapDatasourceSettings) func(http.Header) {
+	switch settings.AuthType {
+	case dsmodel.BasicAuth:
+		credentials := base64.StdEncoding.EncodeToString([]byte(settings.BasicAuthUser + ":" + settings.Secure.BasicAuthPassword))
+		return func(header http.Header) {
+			header.Set("Authorization", "Basic "+credentials)
+		}
+	case dsmodel.BearerAuth:
+		token := settings.Secure.BearerToken
+		return func(header http.Header) {
+			header.Set("Authorization", "Bearer "+token)
+		}
+	default:
+		return nil
+	}
+}
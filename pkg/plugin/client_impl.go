@@ -1,9 +1,12 @@
 package plugin
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	fiapmodel "github.com/SIOS-Technology-Inc/go-fiap-client/pkg/fiap/model"
@@ -23,63 +26,116 @@ var (
 type ClientImpl struct {
 	Client   fiap.Fetcher
 	Settings *dsmodel.FiapDatasourceSettings
+	// HTTPClient sends the requests of the health check. http.DefaultClient is used when it is nil.
+	HTTPClient *http.Client
+	// RequestHeaderFn modifies the header of the health check request when it is not nil.
+	RequestHeaderFn func(http.Header)
+
+	// endpoints fails over the requests among the endpoints, or is nil when only Url is configured.
+	endpoints *endpointPool
 }
 
 func CreateFiapApiClient(settings *dsmodel.FiapDatasourceSettings) (dsmodel.FiapApiClient, error) {
-	return &ClientImpl{Client: &fiap.FetchClient{ConnectionURL: settings.Url}, Settings: settings}, nil
+	httpClient, err := newHTTPClient(settings)
+	if err != nil {
+		return nil, errors.Wrap(err, "http client")
+	}
+	requestHeaderFn, err := newRequestHeaderFn(settings)
+	if err != nil {
+		return nil, errors.Wrap(err, "request headers")
+	}
+	failbackInterval, err := settings.GetFailbackInterval()
+	if err != nil {
+		return nil, err
+	}
+	connectTimeout, readTimeout, err := settings.Timeouts()
+	if err != nil {
+		return nil, err
+	}
+	fetchers := make([]*FetchClient, 0)
+	for _, url := range settings.Endpoints() {
+		fetchers = append(fetchers, &FetchClient{ConnectionURL: url, HTTPClient: httpClient, RequestHeaderFn: requestHeaderFn})
+	}
+	cli := &ClientImpl{
+		Client:          fetchers[0],
+		Settings:        settings,
+		HTTPClient:      httpClient,
+		RequestHeaderFn: requestHeaderFn,
+	}
+	if len(fetchers) > 1 {
+		cli.endpoints = newEndpointPool(fetchers)
+		// a probe ends before the next one, and waits no longer than a request for the response.
+		cli.endpoints.startProbing(failbackInterval, min(failbackInterval, connectTimeout+readTimeout), cli.probeEndpoint)
+	}
+	return cli, nil
 }
 
-func (cli *ClientImpl) CheckHealth() (*backend.CheckHealthResult, error) {
-	backend.Logger.Debug("Start to check health", "connectionURL", cli.Settings.Url)
-	resp, err := http.Head(cli.Settings.Url)
-	defer resp.Body.Close()
-	if err != nil {
-		backend.Logger.Error("Failed to check health", "error", err, "response", resp)
-		return &backend.CheckHealthResult{
-			Status:  backend.HealthStatusError,
-			Message: "Failed to check health. Please see logs for details.",
-		}, nil
-	} else if resp.StatusCode > 299 {
-		backend.Logger.Error("URL returns bad status code", "statusCode", resp.StatusCode, "response", resp)
-		return &backend.CheckHealthResult{
-			Status:  backend.HealthStatusError,
-			Message: fmt.Sprintf("URL returns status code %d. Please see logs for details.", resp.StatusCode),
-		}, nil
-	}
-
-	backend.Logger.Debug("Succeed to check health", "statusCode", resp.StatusCode, "response", resp)
-	return &backend.CheckHealthResult{
-		Status:  backend.HealthStatusOk,
-		Message: "Data source is working",
-	}, nil
-}
-
-func (cli *ClientImpl) FetchWithDateRange(resp *backend.DataResponse, dataRange dsmodel.DataRangeType, fromTime *time.Time, toTime *time.Time, pointIDs []dsmodel.PointID, query *backend.DataQuery) error {
-	fetchErrors := make([]error, 0)
+// Close stops the periodic probes of the failed endpoints.
+func (cli *ClientImpl) Close() error {
+	if cli.endpoints != nil {
+		cli.endpoints.Close()
+	}
+	return nil
+}
 
-	var (
-		pointSets map[string](fiapmodel.ProcessedPointSet)
-		points    map[string]([]fiapmodel.Value)
-		fiapErr   *fiapmodel.Error
-		err       error
-	)
-	switch dataRange {
-	case dsmodel.Period:
-		pointSets, points, fiapErr, err = cli.Client.FetchDateRange(fromTime, toTime, extractPointIDValues(pointIDs)...)
-	case dsmodel.Latest:
-		pointSets, points, fiapErr, err = cli.Client.FetchLatest(fromTime, toTime, extractPointIDValues(pointIDs)...)
-	case dsmodel.Oldest:
-		pointSets, points, fiapErr, err = cli.Client.FetchOldest(fromTime, toTime, extractPointIDValues(pointIDs)...)
+func (cli *ClientImpl) FetchWithDateRange(ctx context.Context, resp *backend.DataResponse, dataRange dsmodel.DataRangeType, fromTime *time.Time, toTime *time.Time, pointIDs []dsmodel.PointID, query *backend.DataQuery, options *dsmodel.QueryOptions) error {
+	if options == nil {
+		options = &dsmodel.QueryOptions{}
 	}
+	fetchErrors := make([]error, 0)
+	alias, err := newAliasTemplate(options.AliasTemplate, options.AliasPattern)
 	if err != nil {
-		fetchErrors = append(fetchErrors, err)
+		return errors.Wrapf(err, "alias pattern '%s'", options.AliasPattern)
 	}
-	if fiapErr != nil {
-		fetchErrors = append(fetchErrors, errors.Newf("fiap error: type %s, value %s", fiapErr.Type, fiapErr.Value))
+
+	// points sharing the same data range and time range are fetched in one request.
+	// all the requests of the query share one budget of MaxRecords.
+	groups := groupPointIDs(dataRange, fromTime, toTime, pointIDs)
+	budget := cli.newRecordBudget()
+	for _, group := range groups {
+		group.budget = budget
+		fiapErr, err := cli.fetchGroup(ctx, group)
+		if err != nil {
+			fetchErrors = append(fetchErrors, err)
+		}
+		if fiapErr != nil {
+			fetchErrors = append(fetchErrors, errors.Newf("fiap error: type %s, value %s", fiapErr.Type, fiapErr.Value))
+		}
+	}
+
+	pointGroups := make([]*pointIDGroup, len(pointIDs))
+	for _, group := range groups {
+		for _, index := range group.indexes {
+			pointGroups[index] = group
+		}
 	}
 
-	for _, pointID := range pointIDs {
-		if _, ok := pointSets[pointID.Value]; ok {
+	for i, pointID := range pointIDs {
+		pointSets, points := pointGroups[i].pointSets, pointGroups[i].points
+		if pointSet, ok := pointSets[pointID.Value]; ok {
+			if options.ExpandPointSets {
+				leaves, err := cli.expandPointSet(ctx, pointGroups[i], pointID.Value, pointSet)
+				if err != nil {
+					fetchErrors = append(fetchErrors, err)
+				}
+				for _, leaf := range leaves {
+					frame, valueField := newPointFrame(query.RefID, leaf.id, leaf.values, cli.valueMappingOf(nil, leaf.values), options)
+					valueField.Labels = data.Labels{"path": strings.Join(leaf.path, " > ")}
+					if alias != nil {
+						setDisplayName(valueField, alias.render(query.RefID, leaf.id, valueField.Labels))
+					}
+					if err := cli.applyUnit(valueField, leaf.id, dsmodel.PointUnit{}); err != nil {
+						fetchErrors = append(fetchErrors, err)
+					}
+					if leaf.truncated {
+						cli.appendTruncatedNotice(frame)
+					}
+					cli.setAttemptsMeta(frame, leaf.attempts)
+					setEndpointMeta(frame, leaf.endpoint)
+					resp.Frames = append(resp.Frames, frame)
+				}
+				continue
+			}
 			fetchErrors = append(fetchErrors, errors.Newf("point id '%s' provides point sets", pointID.Value))
 		}
 		if _, ok := points[pointID.Value]; !ok {
@@ -87,24 +143,20 @@ func (cli *ClientImpl) FetchWithDateRange(resp *backend.DataResponse, dataRange
 			continue
 		}
 
-		// create data frame response.
-		// For an overview on data frames and how grafana handles them:
-		// https://grafana.com/developers/plugin-tools/introduction/data-frames
-		frame := data.NewFrame(fmt.Sprintf("%s:%s", query.RefID, pointID.Value))
-
-		// add fields.
-		if times, values, convErr := pointsToFloatColumns(points[pointID.Value]); convErr == nil {
-			frame.Fields = append(frame.Fields,
-				data.NewField("time", nil, times),
-				data.NewField(pointID.Value, nil, values),
-			)
-		} else {
-			times, values := pointsToDefaultColumns(points[pointID.Value])
-			frame.Fields = append(frame.Fields,
-				data.NewField("time", nil, times),
-				data.NewField(pointID.Value, nil, values),
-			)
+		frame, valueField := newPointFrame(query.RefID, pointID.Value, points[pointID.Value], cli.valueMappingOf(pointID.ValueMapping, points[pointID.Value]), options)
+		if pointID.Alias != "" {
+			setDisplayName(valueField, pointID.Alias)
+		} else if alias != nil {
+			setDisplayName(valueField, alias.render(query.RefID, pointID.Value, valueField.Labels))
+		}
+		if err := cli.applyUnit(valueField, pointID.Value, pointID.PointUnit); err != nil {
+			fetchErrors = append(fetchErrors, err)
 		}
+		if pointGroups[i].truncated[pointID.Value] {
+			cli.appendTruncatedNotice(frame)
+		}
+		cli.setAttemptsMeta(frame, pointGroups[i].attempts)
+		setEndpointMeta(frame, pointGroups[i].endpoint)
 
 		resp.Frames = append(resp.Frames, frame)
 	}
@@ -112,6 +164,429 @@ func (cli *ClientImpl) FetchWithDateRange(resp *backend.DataResponse, dataRange
 	return errors.Join(fetchErrors...)
 }
 
+// setAttemptsMeta records the number of attempts of the request of the frame when requests are retried.
+func (cli *ClientImpl) setAttemptsMeta(frame *data.Frame, attempts int) {
+	if cli.Settings != nil && cli.Settings.Retry.Enabled() {
+		setFrameCustomMeta(frame, "attempts", attempts)
+	}
+}
+
+// setEndpointMeta records the URL of the endpoint which served the frame when standby endpoints are configured.
+func setEndpointMeta(frame *data.Frame, ep *endpoint) {
+	if ep != nil {
+		setFrameCustomMeta(frame, "endpoint", ep.url)
+	}
+}
+
+// request calls the request with the fetcher of each endpoint in the order of the failover,
+// until it succeeds or fails with an error which is not transient, and returns the endpoint of the last call.
+// Only the pinned endpoint is requested when it is not nil.
+// Without standby endpoints, the request is called once with the client and the endpoint is nil.
+func (cli *ClientImpl) request(ctx context.Context, pinned *endpoint, call func(fetcher fiap.Fetcher) (*fiapmodel.Error, error)) (ep *endpoint, fiapErr *fiapmodel.Error, err error) {
+	if cli.endpoints == nil {
+		fiapErr, err = call(cli.fetcherOf(ctx))
+		return nil, fiapErr, err
+	}
+	candidates := cli.endpoints.candidates()
+	if pinned != nil {
+		candidates = []*endpoint{pinned}
+	}
+	for _, ep = range candidates {
+		fiapErr, err = call(ep.fetcher.WithContext(ctx))
+		if err != nil && ctx.Err() == nil && isTransientError(err) {
+			cli.endpoints.markFailed(ep, err)
+			continue
+		}
+		if err == nil {
+			cli.endpoints.markHealthy(ep)
+		}
+		return ep, fiapErr, err
+	}
+	return ep, fiapErr, err
+}
+
+// contextFetcher is a fiap.Fetcher whose requests can be bound to a context.
+type contextFetcher interface {
+	fiap.Fetcher
+	WithContext(ctx context.Context) fiap.Fetcher
+}
+
+// fetcherOf returns the fetcher sending the requests with the context,
+// or the client itself when it does not support contexts.
+func (cli *ClientImpl) fetcherOf(ctx context.Context) fiap.Fetcher {
+	if fetcher, ok := cli.Client.(contextFetcher); ok {
+		return fetcher.WithContext(ctx)
+	}
+	return cli.Client
+}
+
+// fiapPointNotFound is the type of the FIAP error of the IDs which the server does not manage.
+const fiapPointNotFound = "POINT_NOT_FOUND"
+
+func (cli *ClientImpl) FetchChildren(ctx context.Context, pointSetID string) ([]string, []string, error) {
+	// the latest value is requested so that the response is small even if the ID is a point.
+	var (
+		pointSets map[string](fiapmodel.ProcessedPointSet)
+		points    map[string]([]fiapmodel.Value)
+	)
+	fiapErr, _, err := cli.retry(ctx, func() (*fiapmodel.Error, error) {
+		_, fiapErr, err := cli.request(ctx, nil, func(fetcher fiap.Fetcher) (fiapErr *fiapmodel.Error, err error) {
+			pointSets, points, fiapErr, err = fetcher.FetchLatest(nil, nil, pointSetID)
+			return fiapErr, err
+		})
+		return fiapErr, err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if fiapErr != nil {
+		err := errors.Newf("fiap error: type %s, value %s", fiapErr.Type, fiapErr.Value)
+		if fiapErr.Type == fiapPointNotFound {
+			err = errors.Mark(err, dsmodel.ErrPointNotFound)
+		}
+		return nil, nil, err
+	}
+	pointSet, ok := pointSets[pointSetID]
+	if !ok {
+		if _, isPoint := points[pointSetID]; isPoint {
+			return nil, nil, errors.Mark(errors.Newf("point id '%s' not provides point sets", pointSetID), dsmodel.ErrNotPointSet)
+		}
+		return nil, nil, errors.Mark(errors.Newf("point id '%s' is not found", pointSetID), dsmodel.ErrPointNotFound)
+	}
+	return pointSet.PointSetID, pointSet.PointID, nil
+}
+
+// newPointFrame creates a frame of the values of the point, and returns it with its value field.
+func newPointFrame(refID string, pointID string, pointArray []fiapmodel.Value, mapping *dsmodel.ValueMapping, options *dsmodel.QueryOptions) (*data.Frame, *data.Field) {
+	// create data frame response.
+	// For an overview on data frames and how grafana handles them:
+	// https://grafana.com/developers/plugin-tools/introduction/data-frames
+	frame := data.NewFrame(fmt.Sprintf("%s:%s", refID, pointID))
+
+	// add fields.
+	var valueField *data.Field
+	if mapping != nil {
+		times, values, valueMappings := pointsToMappedColumns(pointArray, mapping)
+		valueField = data.NewField(pointID, nil, values).SetConfig(&data.FieldConfig{Mappings: valueMappings})
+		frame.Fields = append(frame.Fields, data.NewField("time", nil, times), valueField)
+	} else if options.NullableNumbers {
+		times, values, rejectedValues, rejectedCount := pointsToNullableFloatColumns(pointArray)
+		valueField = data.NewField(pointID, nil, values)
+		frame.Fields = append(frame.Fields, data.NewField("time", nil, times), valueField)
+		if options.KeepRejectedValues {
+			frame.Fields = append(frame.Fields, data.NewField(pointID+rejectedFieldSuffix, nil, rejectedValues))
+		}
+		setFrameCustomMeta(frame, "rejected_samples", rejectedCount)
+	} else if times, values, convErr := pointsToFloatColumns(pointArray); convErr == nil {
+		valueField = data.NewField(pointID, nil, values)
+		frame.Fields = append(frame.Fields, data.NewField("time", nil, times), valueField)
+	} else {
+		times, values := pointsToDefaultColumns(pointArray)
+		valueField = data.NewField(pointID, nil, values)
+		frame.Fields = append(frame.Fields, data.NewField("time", nil, times), valueField)
+	}
+	return frame, valueField
+}
+
+// rejectedFieldSuffix is appended to the point ID for the name of the field holding non-numeric values.
+const rejectedFieldSuffix = " (rejected)"
+
+// setFrameCustomMeta sets the value to the custom metadata of the frame, which is a map keyed by snake case names.
+func setFrameCustomMeta(frame *data.Frame, key string, value interface{}) {
+	if frame.Meta == nil {
+		frame.Meta = &data.FrameMeta{}
+	}
+	custom, ok := frame.Meta.Custom.(map[string]interface{})
+	if !ok {
+		custom = make(map[string]interface{})
+		frame.Meta.Custom = custom
+	}
+	custom[key] = value
+}
+
+func (cli *ClientImpl) appendTruncatedNotice(frame *data.Frame) {
+	frame.AppendNotices(data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text:     fmt.Sprintf("The values of the point are truncated because the query exceeds the maximum number of records (%d).", cli.Settings.MaxRecords),
+	})
+}
+
+// maxPointSetDepth is the maximum depth of point sets walked by expandPointSet.
+const maxPointSetDepth = 16
+
+// expandedPoint is a point found under a point set.
+type expandedPoint struct {
+	id string
+	// path is the IDs of the point sets from the requested one to the parent of the point.
+	path      []string
+	values    []fiapmodel.Value
+	truncated bool
+	attempts  int
+	endpoint  *endpoint
+}
+
+// expandPointSet walks the hierarchy under the point set level by level, and returns the points in it.
+// Each level is fetched in one request with the data range and time range of the group.
+func (cli *ClientImpl) expandPointSet(ctx context.Context, group *pointIDGroup, pointSetID string, pointSet fiapmodel.ProcessedPointSet) ([]expandedPoint, error) {
+	type pointSetNode struct {
+		path     []string
+		pointSet fiapmodel.ProcessedPointSet
+	}
+
+	leaves := make([]expandedPoint, 0)
+	visited := map[string]bool{pointSetID: true}
+	level := []pointSetNode{{path: []string{pointSetID}, pointSet: pointSet}}
+	for depth := 0; len(level) > 0; depth++ {
+		if depth >= maxPointSetDepth {
+			return leaves, errors.Newf("point set '%s' is nested deeper than %d levels", pointSetID, maxPointSetDepth)
+		}
+
+		// collect the children of this level, skipping the ones already seen to avoid cycles.
+		children := &pointIDGroup{dataRange: group.dataRange, fromTime: group.fromTime, toTime: group.toTime, budget: group.budget}
+		parentPaths := make(map[string][]string)
+		for _, node := range level {
+			for _, id := range append(append([]string{}, node.pointSet.PointSetID...), node.pointSet.PointID...) {
+				if visited[id] {
+					continue
+				}
+				visited[id] = true
+				parentPaths[id] = node.path
+				children.pointIDs = append(children.pointIDs, dsmodel.PointID{Value: id})
+			}
+		}
+		if len(children.pointIDs) == 0 {
+			break
+		}
+
+		fiapErr, err := cli.fetchGroup(ctx, children)
+		if err != nil {
+			return leaves, errors.Wrapf(err, "expand point set '%s'", pointSetID)
+		}
+		if fiapErr != nil {
+			return leaves, errors.Newf("expand point set '%s': fiap error: type %s, value %s", pointSetID, fiapErr.Type, fiapErr.Value)
+		}
+
+		next := make([]pointSetNode, 0)
+		for _, child := range children.pointIDs {
+			path := parentPaths[child.Value]
+			if childPointSet, ok := children.pointSets[child.Value]; ok {
+				next = append(next, pointSetNode{path: append(append([]string{}, path...), child.Value), pointSet: childPointSet})
+			}
+			if values, ok := children.points[child.Value]; ok {
+				leaves = append(leaves, expandedPoint{id: child.Value, path: path, values: values, truncated: children.truncated[child.Value], attempts: children.attempts, endpoint: children.endpoint})
+			}
+		}
+		level = next
+	}
+	return leaves, nil
+}
+
+// fetchGroup fetches the point data of the group and stores the results in it.
+func (cli *ClientImpl) fetchGroup(ctx context.Context, group *pointIDGroup) (fiapErr *fiapmodel.Error, err error) {
+	if cli.Settings != nil && cli.Settings.AcceptableSize > 0 {
+		return cli.fetchGroupByPage(ctx, group)
+	}
+
+	fiapErr, group.attempts, err = cli.retry(ctx, func() (fiapErr *fiapmodel.Error, err error) {
+		group.endpoint, fiapErr, err = cli.request(ctx, nil, func(fetcher fiap.Fetcher) (fiapErr *fiapmodel.Error, err error) {
+			switch group.dataRange {
+			case dsmodel.Period:
+				group.pointSets, group.points, fiapErr, err = fetcher.FetchDateRange(group.fromTime, group.toTime, extractPointIDValues(group.pointIDs)...)
+			case dsmodel.Latest:
+				group.pointSets, group.points, fiapErr, err = fetcher.FetchLatest(group.fromTime, group.toTime, extractPointIDValues(group.pointIDs)...)
+			case dsmodel.Oldest:
+				group.pointSets, group.points, fiapErr, err = fetcher.FetchOldest(group.fromTime, group.toTime, extractPointIDValues(group.pointIDs)...)
+			}
+			return fiapErr, err
+		})
+		return fiapErr, err
+	})
+	if err == nil && fiapErr == nil && group.budget.limited() {
+		points := group.points
+		group.points = make(map[string]([]fiapmodel.Value))
+		group.appendRecords(points)
+	}
+	return fiapErr, err
+}
+
+// fetchGroupByPage fetches the point data of the group page by page with the cursor, and collects the pages into the group.
+// Paging stops when values are dropped because the budget of the records runs out.
+func (cli *ClientImpl) fetchGroupByPage(ctx context.Context, group *pointIDGroup) (*fiapmodel.Error, error) {
+	keys := make([]fiapmodel.UserInputKey, len(group.pointIDs))
+	for i := range group.pointIDs {
+		keys[i] = fiapmodel.UserInputKey{
+			ID:              group.pointIDs[i].Value,
+			Gteq:            group.fromTime,
+			Lteq:            group.toTime,
+			MinMaxIndicator: selectTypeOf(group.dataRange),
+		}
+	}
+
+	group.pointSets = make(map[string](fiapmodel.ProcessedPointSet))
+	group.points = make(map[string]([]fiapmodel.Value))
+	cursor := ""
+	for page := 1; ; page++ {
+		if err := ctx.Err(); err != nil {
+			return nil, errors.Wrapf(err, "fetch page %d", page)
+		}
+		var pointSets map[string](fiapmodel.ProcessedPointSet)
+		var points map[string]([]fiapmodel.Value)
+		var nextCursor string
+		// the cursor is valid only on the endpoint of the first page, so that the following pages are pinned to it.
+		var pinned *endpoint
+		if page > 1 {
+			pinned = group.endpoint
+		}
+		fiapErr, attempts, err := cli.retry(ctx, func() (fiapErr *fiapmodel.Error, err error) {
+			group.endpoint, fiapErr, err = cli.request(ctx, pinned, func(fetcher fiap.Fetcher) (fiapErr *fiapmodel.Error, err error) {
+				pointSets, points, nextCursor, fiapErr, err = fetcher.FetchOnce(keys, &fiapmodel.FetchOnceOption{AcceptableSize: cli.Settings.AcceptableSize, Cursor: cursor})
+				return fiapErr, err
+			})
+			return fiapErr, err
+		})
+		group.attempts = max(group.attempts, attempts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "fetch page %d", page)
+		}
+		if fiapErr != nil {
+			return fiapErr, nil
+		}
+
+		for id, pointSet := range pointSets {
+			existing := group.pointSets[id]
+			existing.PointSetID = append(existing.PointSetID, pointSet.PointSetID...)
+			existing.PointID = append(existing.PointID, pointSet.PointID...)
+			group.pointSets[id] = existing
+		}
+		group.appendRecords(points)
+
+		if nextCursor == "" || len(group.truncated) > 0 {
+			break
+		}
+		cursor = nextCursor
+	}
+	backend.Logger.Debug("Finish fetch by page", "records", group.budget.records, "truncated", len(group.truncated))
+	return nil, nil
+}
+
+// recordBudget is the number of values which one query may still fetch, shared by all the requests of the query.
+type recordBudget struct {
+	// max is MaxRecords of the settings, or 0 without limit.
+	max     int
+	records int
+}
+
+func (cli *ClientImpl) newRecordBudget() *recordBudget {
+	if cli.Settings == nil {
+		return &recordBudget{}
+	}
+	return &recordBudget{max: cli.Settings.MaxRecords}
+}
+
+func (b *recordBudget) limited() bool {
+	return b != nil && b.max > 0
+}
+
+// take consumes the budget of at most n values, and returns the number of values which may be kept.
+func (b *recordBudget) take(n int) int {
+	if b.limited() {
+		n = min(n, b.max-b.records)
+	}
+	if b != nil {
+		b.records += n
+	}
+	return n
+}
+
+// appendRecords appends the values of the points to the group as far as the budget allows.
+// The points whose values are dropped are marked truncated.
+func (group *pointIDGroup) appendRecords(points map[string]([]fiapmodel.Value)) {
+	// iterate in the order of point IDs so that the truncation is deterministic.
+	for _, id := range sortedKeys(points) {
+		values := points[id]
+		if kept := group.budget.take(len(values)); kept < len(values) {
+			values = values[:kept]
+			if group.truncated == nil {
+				group.truncated = make(map[string]bool)
+			}
+			group.truncated[id] = true
+		}
+		group.points[id] = append(group.points[id], values...)
+	}
+}
+
+func selectTypeOf(dataRange dsmodel.DataRangeType) fiapmodel.SelectType {
+	switch dataRange {
+	case dsmodel.Latest:
+		return fiapmodel.SelectTypeMaximum
+	case dsmodel.Oldest:
+		return fiapmodel.SelectTypeMinimum
+	default:
+		return fiapmodel.SelectTypeNone
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// pointIDGroup is a set of point IDs which are fetched in one request.
+type pointIDGroup struct {
+	dataRange dsmodel.DataRangeType
+	fromTime  *time.Time
+	toTime    *time.Time
+	pointIDs  []dsmodel.PointID
+	// indexes are the positions of pointIDs in the query.
+	indexes []int
+
+	pointSets map[string](fiapmodel.ProcessedPointSet)
+	points    map[string]([]fiapmodel.Value)
+	// budget is the record budget of the query which the group belongs to.
+	budget *recordBudget
+	// truncated holds the IDs of the points whose values are dropped by the budget.
+	truncated map[string]bool
+	// attempts is the largest number of attempts of the requests of the group.
+	attempts int
+	// endpoint served the group, or is nil without standby endpoints.
+	endpoint *endpoint
+}
+
+// groupPointIDs groups point IDs by their data range and time range, keeping the order of the first appearance.
+func groupPointIDs(dataRange dsmodel.DataRangeType, fromTime *time.Time, toTime *time.Time, pointIDs []dsmodel.PointID) []*pointIDGroup {
+	groups := make([]*pointIDGroup, 0)
+	for i := range pointIDs {
+		pointDataRange := pointIDs[i].GetDataRange(dataRange)
+		pointFromTime, pointToTime := pointIDs[i].GetTimeRange(fromTime, toTime)
+
+		var group *pointIDGroup
+		for _, g := range groups {
+			if g.dataRange == pointDataRange && equalTimePtr(g.fromTime, pointFromTime) && equalTimePtr(g.toTime, pointToTime) {
+				group = g
+				break
+			}
+		}
+		if group == nil {
+			group = &pointIDGroup{dataRange: pointDataRange, fromTime: pointFromTime, toTime: pointToTime}
+			groups = append(groups, group)
+		}
+		group.pointIDs = append(group.pointIDs, pointIDs[i])
+		group.indexes = append(group.indexes, i)
+	}
+	return groups
+}
+
+func equalTimePtr(a *time.Time, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
 func extractPointIDValues(pointIDs []dsmodel.PointID) []string {
 	retVal := make([]string, len(pointIDs))
 	for i := range pointIDs {
@@ -139,6 +614,25 @@ func pointsToFloatColumns(pointArray []fiapmodel.Value) ([]time.Time, []float64,
 	return times, values, nil
 }
 
+// pointsToNullableFloatColumns converts the values to floats, leaving nil for the values which cannot be parsed.
+// The values which cannot be parsed are returned in rejectedValues at the same rows, with their count.
+func pointsToNullableFloatColumns(pointArray []fiapmodel.Value) (times []time.Time, values []*float64, rejectedValues []*string, rejectedCount int) {
+	times = make([]time.Time, len(pointArray))
+	values = make([]*float64, len(pointArray))
+	rejectedValues = make([]*string, len(pointArray))
+	for i := range pointArray {
+		times[i] = pointArray[i].Time
+		if floatValue, err := strconv.ParseFloat(pointArray[i].Value, 64); err == nil {
+			values[i] = &floatValue
+		} else {
+			rejectedValue := pointArray[i].Value
+			rejectedValues[i] = &rejectedValue
+			rejectedCount++
+		}
+	}
+	return times, values, rejectedValues, rejectedCount
+}
+
 func pointsToDefaultColumns(pointArray []fiapmodel.Value) ([]time.Time, []string) {
 	var (
 		times  = make([]time.Time, len(pointArray))
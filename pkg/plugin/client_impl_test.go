@@ -1,6 +1,7 @@
 package plugin
 
 import (
+	"context"
 	"fmt"
 	"strings"
 	"testing"
@@ -18,6 +19,7 @@ import (
 var _ fiap.Fetcher = (*mockFetchClient)(nil)
 
 type fetchClientArguments struct {
+	dataRange dsmodel.DataRangeType
 	fromDate  *time.Time
 	untilDate *time.Time
 	ids       []string
@@ -29,11 +31,24 @@ type fetchClientResults struct {
 	fiapErr   *fiapmodel.Error
 }
 
+// fetchOncePage is a response of FetchOnce for the cursor.
+type fetchOncePage struct {
+	points     map[string]([]fiapmodel.Value)
+	nextCursor string
+}
+
 type mockFetchClient struct {
 	failLatest, failOldest, failDateRange bool
 
+	// pages are the responses of FetchOnce, keyed by the cursor.
+	pages map[string]fetchOncePage
+	// fetchOnceArguments holds the keys and options of all calls of FetchOnce in order.
+	fetchOnceArguments []fetchOnceArguments
+
 	actualArguments *fetchClientArguments
-	results         *fetchClientResults
+	// argumentsHistory holds the arguments of all calls in order.
+	argumentsHistory []*fetchClientArguments
+	results          *fetchClientResults
 }
 
 func (*mockFetchClient) Fetch(keys []fiapmodel.UserInputKey, option *fiapmodel.FetchOption) (pointSets map[string]fiapmodel.ProcessedPointSet, points map[string][]fiapmodel.Value, fiapErr *fiapmodel.Error, err error) {
@@ -44,8 +59,21 @@ func (*mockFetchClient) FetchByIdsWithKey(key fiapmodel.UserInputKeyNoID, ids ..
 	return nil, nil, nil, errors.New("unimplemented")
 }
 
-func (*mockFetchClient) FetchOnce(keys []fiapmodel.UserInputKey, option *fiapmodel.FetchOnceOption) (pointSets map[string]fiapmodel.ProcessedPointSet, points map[string][]fiapmodel.Value, cursor string, fiapErr *fiapmodel.Error, err error) {
-	return nil, nil, "", nil, errors.New("unimplemented")
+type fetchOnceArguments struct {
+	keys   []fiapmodel.UserInputKey
+	option fiapmodel.FetchOnceOption
+}
+
+func (f *mockFetchClient) FetchOnce(keys []fiapmodel.UserInputKey, option *fiapmodel.FetchOnceOption) (pointSets map[string]fiapmodel.ProcessedPointSet, points map[string][]fiapmodel.Value, cursor string, fiapErr *fiapmodel.Error, err error) {
+	if f.pages == nil {
+		return nil, nil, "", nil, errors.New("unimplemented")
+	}
+	f.fetchOnceArguments = append(f.fetchOnceArguments, fetchOnceArguments{keys: keys, option: *option})
+	page, ok := f.pages[option.Cursor]
+	if !ok {
+		return nil, nil, "", nil, errors.Newf("test unknown cursor %s", option.Cursor)
+	}
+	return map[string]fiapmodel.ProcessedPointSet{}, page.points, page.nextCursor, nil, nil
 }
 
 func (f *mockFetchClient) FetchDateRange(fromDate *time.Time, untilDate *time.Time, ids ...string) (pointSets map[string]fiapmodel.ProcessedPointSet, points map[string][]fiapmodel.Value, fiapErr *fiapmodel.Error, err error) {
@@ -53,10 +81,12 @@ func (f *mockFetchClient) FetchDateRange(fromDate *time.Time, untilDate *time.Ti
 		return nil, nil, nil, errors.New("test FetchLatest error")
 	}
 	f.actualArguments = &fetchClientArguments{
+		dataRange: dsmodel.Period,
 		fromDate:  fromDate,
 		untilDate: untilDate,
 		ids:       ids,
 	}
+	f.argumentsHistory = append(f.argumentsHistory, f.actualArguments)
 	return f.results.pointSets, f.results.points, f.results.fiapErr, nil
 }
 
@@ -65,10 +95,12 @@ func (f *mockFetchClient) FetchLatest(fromDate *time.Time, untilDate *time.Time,
 		return nil, nil, nil, errors.New("test FetchLatest error")
 	}
 	f.actualArguments = &fetchClientArguments{
+		dataRange: dsmodel.Latest,
 		fromDate:  fromDate,
 		untilDate: untilDate,
 		ids:       ids,
 	}
+	f.argumentsHistory = append(f.argumentsHistory, f.actualArguments)
 	return f.results.pointSets, f.results.points, f.results.fiapErr, nil
 }
 
@@ -77,13 +109,33 @@ func (f *mockFetchClient) FetchOldest(fromDate *time.Time, untilDate *time.Time,
 		return nil, nil, nil, errors.New("test FetchOldest error")
 	}
 	f.actualArguments = &fetchClientArguments{
+		dataRange: dsmodel.Oldest,
 		fromDate:  fromDate,
 		untilDate: untilDate,
 		ids:       ids,
 	}
+	f.argumentsHistory = append(f.argumentsHistory, f.actualArguments)
 	return f.results.pointSets, f.results.points, f.results.fiapErr, nil
 }
 
+// valuesOf returns the values at every hour from 00:00 of the day in May 2024.
+func valuesOf(day int, values ...string) []fiapmodel.Value {
+	retVal := make([]fiapmodel.Value, len(values))
+	for i := range values {
+		retVal[i] = fiapmodel.Value{Time: time.Date(2024, 5, day, i, 0, 0, 0, time.UTC), Value: values[i]}
+	}
+	return retVal
+}
+
+// newDateRangeClient returns the client whose FetchDateRange returns the points, and the mock to check the arguments.
+func newDateRangeClient(points map[string][]fiapmodel.Value, settings *dsmodel.FiapDatasourceSettings) (*ClientImpl, *mockFetchClient) {
+	fetchClient := &mockFetchClient{failLatest: true, failOldest: true, failDateRange: false, results: &fetchClientResults{
+		pointSets: map[string]fiapmodel.ProcessedPointSet{},
+		points:    points,
+	}}
+	return &ClientImpl{Client: fetchClient, Settings: settings}, fetchClient
+}
+
 func TestFetchWithDateRange(t *testing.T) {
 	query := &backend.DataQuery{
 		RefID: "A",
@@ -134,7 +186,7 @@ func TestFetchWithDateRange(t *testing.T) {
 				}
 
 				resp := &backend.DataResponse{}
-				err := cli.FetchWithDateRange(resp, dataRange, &fromTime, &toTime, pointIDs, query)
+				err := cli.FetchWithDateRange(context.Background(), resp, dataRange, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{})
 				if err != nil {
 					t.Error(err)
 				}
@@ -183,7 +235,7 @@ func TestFetchWithDateRange(t *testing.T) {
 				}
 
 				resp := &backend.DataResponse{}
-				err := cli.FetchWithDateRange(resp, dataRange, &fromTime, &toTime, pointIDs, query)
+				err := cli.FetchWithDateRange(context.Background(), resp, dataRange, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{})
 				if err != nil {
 					t.Error(err)
 				}
@@ -232,7 +284,7 @@ func TestFetchWithDateRange(t *testing.T) {
 				}
 
 				resp := &backend.DataResponse{}
-				err := cli.FetchWithDateRange(resp, dataRange, &fromTime, &toTime, pointIDs, query)
+				err := cli.FetchWithDateRange(context.Background(), resp, dataRange, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{})
 				if err != nil {
 					t.Error(err)
 				}
@@ -289,7 +341,7 @@ func TestFetchWithDateRange(t *testing.T) {
 				}
 
 				resp := &backend.DataResponse{}
-				err := cli.FetchWithDateRange(resp, dataRange, &fromTime, &toTime, pointIDs, query)
+				err := cli.FetchWithDateRange(context.Background(), resp, dataRange, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{})
 				if err != nil {
 					t.Error(err)
 				}
@@ -349,7 +401,7 @@ func TestFetchWithDateRange(t *testing.T) {
 				}
 
 				resp := &backend.DataResponse{}
-				err := cli.FetchWithDateRange(resp, dataRange, &fromTime, &toTime, pointIDs, query)
+				err := cli.FetchWithDateRange(context.Background(), resp, dataRange, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{})
 				if err != nil {
 					t.Error(err)
 				}
@@ -406,7 +458,7 @@ func TestFetchWithDateRange(t *testing.T) {
 			}
 
 			resp := &backend.DataResponse{}
-			err := cli.FetchWithDateRange(resp, dataRange, &fromTime, &toTime, pointIDs, query)
+			err := cli.FetchWithDateRange(context.Background(), resp, dataRange, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{})
 			if err != nil {
 				t.Error(err)
 			}
@@ -437,6 +489,72 @@ func TestFetchWithDateRange(t *testing.T) {
 				}
 			}
 		})
+		t.Run("PointOverrides", func(t *testing.T) {
+			dataRange := dsmodel.Period
+			fetchClient.failLatest, fetchClient.failOldest, fetchClient.failDateRange = false, true, false
+			fetchClient.results = &fetchClientResults{
+				pointSets: map[string]fiapmodel.ProcessedPointSet{},
+				points: map[string][]fiapmodel.Value{
+					"id_a": {{Time: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Value: "1"}},
+					"id_b": {{Time: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), Value: "2"}},
+					"id_c": {{Time: time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC), Value: "3"}},
+					"id_d": {{Time: time.Date(2024, 5, 4, 0, 0, 0, 0, time.UTC), Value: "4"}},
+				},
+				fiapErr: nil,
+			}
+			overrideFromTime := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+			pointIDs := []dsmodel.PointID{
+				{Value: "id_a"},
+				{Value: "id_b", DataRange: dsmodel.Latest, Alias: "meter"},
+				{Value: "id_c"},
+				{Value: "id_d", StartTime: &dsmodel.LinkedTime{RawTime: "2024-04-01 00:00:00"}, FromTime: &overrideFromTime},
+			}
+			fetchClient.argumentsHistory = nil
+
+			resp := &backend.DataResponse{}
+			err := cli.FetchWithDateRange(context.Background(), resp, dataRange, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{})
+			if err != nil {
+				t.Error(err)
+			}
+
+			checkFrame(resp, fetchClient.results.points, query, data.FieldTypeFloat64, func(message string) {
+				t.Error(message)
+			})
+			expectedArguments := []fetchClientArguments{
+				{dataRange: dsmodel.Period, fromDate: &fromTime, untilDate: &toTime, ids: []string{"id_a", "id_c"}},
+				{dataRange: dsmodel.Latest, fromDate: &fromTime, untilDate: &toTime, ids: []string{"id_b"}},
+				{dataRange: dsmodel.Period, fromDate: &overrideFromTime, untilDate: &toTime, ids: []string{"id_d"}},
+			}
+			if len(fetchClient.argumentsHistory) != len(expectedArguments) {
+				t.Fatalf("expected fetch count is %d but %d", len(expectedArguments), len(fetchClient.argumentsHistory))
+			}
+			for i, expected := range expectedArguments {
+				actual := fetchClient.argumentsHistory[i]
+				if actual.dataRange != expected.dataRange {
+					t.Errorf("expected dataRange of fetch[%d] is %s but %s", i, expected.dataRange, actual.dataRange)
+				}
+				if actual.fromDate == nil || !actual.fromDate.Equal(*expected.fromDate) {
+					t.Errorf("expected fromDate of fetch[%d] is %s but %v", i, expected.fromDate.Format("2006-01-02 15:04:05"), actual.fromDate)
+				}
+				if actual.untilDate == nil || !actual.untilDate.Equal(*expected.untilDate) {
+					t.Errorf("expected untilDate of fetch[%d] is %s but %v", i, expected.untilDate.Format("2006-01-02 15:04:05"), actual.untilDate)
+				}
+				if strings.Join(actual.ids, ",") != strings.Join(expected.ids, ",") {
+					t.Errorf("expected ids of fetch[%d] are %v but %v", i, expected.ids, actual.ids)
+				}
+			}
+			if len(resp.Frames) != len(pointIDs) {
+				t.Fatalf("expected frame count is %d but %d", len(pointIDs), len(resp.Frames))
+			}
+			for i := range pointIDs {
+				if expectedName := query.RefID + ":" + pointIDs[i].Value; resp.Frames[i].Name != expectedName {
+					t.Errorf("expected frame[%d] is %s but %s", i, expectedName, resp.Frames[i].Name)
+				}
+			}
+			if config := resp.Frames[1].Fields[1].Config; config == nil || config.DisplayNameFromDS != "meter" {
+				t.Errorf("expected display name of %s is %s but %v", "id_b", "meter", config)
+			}
+		})
 
 	})
 	t.Run("Error", func(t *testing.T) {
@@ -450,7 +568,7 @@ func TestFetchWithDateRange(t *testing.T) {
 			fetchClient.results = nil
 
 			resp := &backend.DataResponse{}
-			err := cli.FetchWithDateRange(resp, dataRange, &fromTime, &toTime, pointIDs, query)
+			err := cli.FetchWithDateRange(context.Background(), resp, dataRange, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{})
 			if expectedErr := "test FetchLatest error"; err == nil {
 				t.Errorf("expected error is %s but nil", expectedErr)
 			} else if !strings.Contains(err.Error(), expectedErr) {
@@ -487,7 +605,7 @@ func TestFetchWithDateRange(t *testing.T) {
 			}
 
 			resp := &backend.DataResponse{}
-			err := cli.FetchWithDateRange(resp, dataRange, &fromTime, &toTime, pointIDs, query)
+			err := cli.FetchWithDateRange(context.Background(), resp, dataRange, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{})
 			if expectedErr := "fiap error: type test_type, value test_value"; err == nil {
 				t.Errorf("expected error is %s but nil", expectedErr)
 			} else if !strings.Contains(err.Error(), expectedErr) {
@@ -540,7 +658,7 @@ func TestFetchWithDateRange(t *testing.T) {
 			}
 
 			resp := &backend.DataResponse{}
-			err := cli.FetchWithDateRange(resp, dataRange, &fromTime, &toTime, pointIDs, query)
+			err := cli.FetchWithDateRange(context.Background(), resp, dataRange, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{})
 			if expectedErr1, expectedErr2 := "point id 'id_w' provides point sets", "point id 'id_w' not provides point data"; err == nil {
 				t.Errorf("expected error is %s and %s but nil", expectedErr1, expectedErr2)
 			} else if !strings.Contains(err.Error(), expectedErr1) {
@@ -578,6 +696,322 @@ func TestFetchWithDateRange(t *testing.T) {
 	})
 }
 
+func TestFetchWithDateRangeExpandPointSets(t *testing.T) {
+	query := &backend.DataQuery{
+		RefID: "A",
+	}
+	fromTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	toTime := time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC)
+	// the mock returns all point sets and points regardless of the requested IDs.
+	fetchClient := mockFetchClient{failLatest: true, failOldest: true, failDateRange: false, results: &fetchClientResults{
+		pointSets: map[string]fiapmodel.ProcessedPointSet{
+			"id_root": {PointSetID: []string{"id_sub"}, PointID: []string{"id_a"}},
+			// id_root is listed again to check that cycles are skipped.
+			"id_sub": {PointSetID: []string{"id_root"}, PointID: []string{"id_b", "id_c"}},
+		},
+		points: map[string][]fiapmodel.Value{
+			"id_a": {{Time: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Value: "1"}},
+			"id_b": {{Time: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), Value: "2"}},
+			"id_c": {{Time: time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC), Value: "3"}},
+		},
+	}}
+	cli := ClientImpl{Client: &fetchClient}
+
+	resp := &backend.DataResponse{}
+	err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, []dsmodel.PointID{{Value: "id_root"}}, query, &dsmodel.QueryOptions{ExpandPointSets: true})
+	if err != nil {
+		t.Error(err)
+	}
+
+	expectedIDs := [][]string{{"id_root"}, {"id_sub", "id_a"}, {"id_b", "id_c"}}
+	if len(fetchClient.argumentsHistory) != len(expectedIDs) {
+		t.Fatalf("expected fetch count is %d but %d", len(expectedIDs), len(fetchClient.argumentsHistory))
+	}
+	for i := range expectedIDs {
+		if actual := fetchClient.argumentsHistory[i]; strings.Join(actual.ids, ",") != strings.Join(expectedIDs[i], ",") {
+			t.Errorf("expected ids of fetch[%d] are %v but %v", i, expectedIDs[i], actual.ids)
+		}
+		if actual := fetchClient.argumentsHistory[i]; !actual.fromDate.Equal(fromTime) || !actual.untilDate.Equal(toTime) {
+			t.Errorf("expected time range of fetch[%d] is the same as the query but %v - %v", i, actual.fromDate, actual.untilDate)
+		}
+	}
+	checkFrame(resp, fetchClient.results.points, query, data.FieldTypeFloat64, func(message string) {
+		t.Error(message)
+	})
+	expectedPaths := map[string]string{"A:id_a": "id_root", "A:id_b": "id_root > id_sub", "A:id_c": "id_root > id_sub"}
+	for _, frame := range resp.Frames {
+		if actual := frame.Fields[1].Labels["path"]; actual != expectedPaths[frame.Name] {
+			t.Errorf("expected path of frame '%s' is %s but %s", frame.Name, expectedPaths[frame.Name], actual)
+		}
+	}
+}
+
+func TestFetchWithDateRangeNullableNumbers(t *testing.T) {
+	query := &backend.DataQuery{
+		RefID: "A",
+	}
+	fromTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	toTime := time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC)
+	fetchClient := mockFetchClient{failLatest: true, failOldest: true, failDateRange: false, results: &fetchClientResults{
+		pointSets: map[string]fiapmodel.ProcessedPointSet{},
+		points: map[string][]fiapmodel.Value{
+			"id_a": {
+				{Time: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Value: "33.4"},
+				{Time: time.Date(2024, 5, 2, 0, 0, 0, 0, time.UTC), Value: "ERR"},
+				{Time: time.Date(2024, 5, 3, 0, 0, 0, 0, time.UTC), Value: "1"},
+			},
+		},
+	}}
+	cli := ClientImpl{Client: &fetchClient}
+	pointIDs := []dsmodel.PointID{{Value: "id_a"}}
+
+	t.Run("WithoutRejectedValues", func(t *testing.T) {
+		resp := &backend.DataResponse{}
+		if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{NullableNumbers: true}); err != nil {
+			t.Error(err)
+		}
+		checkFrame(resp, fetchClient.results.points, query, data.FieldTypeNullableFloat64, func(message string) {
+			t.Error(message)
+		})
+		valueField := resp.Frames[0].Fields[1]
+		for i, expected := range []*float64{pointerOf(33.4), nil, pointerOf(1.0)} {
+			if actual := valueField.At(i).(*float64); (actual == nil) != (expected == nil) || (actual != nil && *actual != *expected) {
+				t.Errorf("expected value[%d] is %v but %v", i, expected, actual)
+			}
+		}
+		if custom, ok := resp.Frames[0].Meta.Custom.(map[string]interface{}); !ok || custom["rejected_samples"] != 1 {
+			t.Errorf("expected rejected samples is %d but %v", 1, resp.Frames[0].Meta.Custom)
+		}
+	})
+	t.Run("WithRejectedValues", func(t *testing.T) {
+		resp := &backend.DataResponse{}
+		if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{NullableNumbers: true, KeepRejectedValues: true}); err != nil {
+			t.Error(err)
+		}
+		if len(resp.Frames) != 1 || len(resp.Frames[0].Fields) != 3 {
+			t.Fatalf("expected one frame with %d fields", 3)
+		}
+		rejectedField := resp.Frames[0].Fields[2]
+		if rejectedField.Name != "id_a (rejected)" || rejectedField.Type() != data.FieldTypeNullableString {
+			t.Errorf("expected field is %s of %s but %s of %s", "id_a (rejected)", data.FieldTypeNullableString, rejectedField.Name, rejectedField.Type())
+		}
+		for i, expected := range []*string{nil, pointerOf("ERR"), nil} {
+			if actual := rejectedField.At(i).(*string); (actual == nil) != (expected == nil) || (actual != nil && *actual != *expected) {
+				t.Errorf("expected rejected value[%d] is %v but %v", i, expected, actual)
+			}
+		}
+	})
+}
+
+func TestFetchChildren(t *testing.T) {
+	t.Run("Normal", func(t *testing.T) {
+		fetchClient := mockFetchClient{failLatest: false, failOldest: true, failDateRange: true, results: &fetchClientResults{
+			pointSets: map[string]fiapmodel.ProcessedPointSet{
+				"id_w": {PointSetID: []string{"id_x"}, PointID: []string{"id_a", "id_b"}},
+			},
+			points: map[string][]fiapmodel.Value{},
+		}}
+		cli := ClientImpl{Client: &fetchClient}
+
+		pointSetIDs, pointIDs, err := cli.FetchChildren(context.Background(), "id_w")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(pointSetIDs, ",") != "id_x" {
+			t.Errorf("expected point set IDs are %s but %v", "id_x", pointSetIDs)
+		}
+		if strings.Join(pointIDs, ",") != "id_a,id_b" {
+			t.Errorf("expected point IDs are %s but %v", "id_a,id_b", pointIDs)
+		}
+		if ids := fetchClient.actualArguments.ids; len(ids) != 1 || ids[0] != "id_w" {
+			t.Errorf("expected requested IDs are %s but %v", "id_w", ids)
+		}
+	})
+	t.Run("Error", func(t *testing.T) {
+		t.Run("NotPointSet", func(t *testing.T) {
+			fetchClient := mockFetchClient{failLatest: false, failOldest: true, failDateRange: true, results: &fetchClientResults{
+				pointSets: map[string]fiapmodel.ProcessedPointSet{},
+				points:    map[string][]fiapmodel.Value{"id_a": {}},
+			}}
+			cli := ClientImpl{Client: &fetchClient}
+
+			_, _, err := cli.FetchChildren(context.Background(), "id_a")
+			if expectedErr := "point id 'id_a' not provides point sets"; err == nil {
+				t.Errorf("expected error is %s but nil", expectedErr)
+			} else if !strings.Contains(err.Error(), expectedErr) {
+				t.Errorf("expected error is %s but %s", expectedErr, err.Error())
+			} else if !errors.Is(err, dsmodel.ErrNotPointSet) {
+				t.Errorf("expected error is marked %v but %v", dsmodel.ErrNotPointSet, err)
+			}
+		})
+		t.Run("NotFound", func(t *testing.T) {
+			for name, results := range map[string]*fetchClientResults{
+				"NoElement": {pointSets: map[string]fiapmodel.ProcessedPointSet{}, points: map[string][]fiapmodel.Value{}},
+				"FiapError": {fiapErr: &fiapmodel.Error{Type: "POINT_NOT_FOUND", Value: "test_value"}},
+			} {
+				fetchClient := mockFetchClient{failLatest: false, failOldest: true, failDateRange: true, results: results}
+				cli := ClientImpl{Client: &fetchClient}
+
+				if _, _, err := cli.FetchChildren(context.Background(), "id_a"); !errors.Is(err, dsmodel.ErrPointNotFound) {
+					t.Errorf("expected error of %s is marked %v but %v", name, dsmodel.ErrPointNotFound, err)
+				}
+			}
+		})
+		t.Run("FetchFailed", func(t *testing.T) {
+			fetchClient := mockFetchClient{failLatest: true, failOldest: true, failDateRange: true}
+			cli := ClientImpl{Client: &fetchClient}
+
+			if _, _, err := cli.FetchChildren(context.Background(), "id_a"); err == nil {
+				t.Error("FetchChildren must return an error")
+			}
+		})
+	})
+}
+
+// checkTruncated checks the rows of the frames, and that only the truncated ones have the notice of the truncation.
+func checkTruncated(t *testing.T, resp *backend.DataResponse, expectedRows []int, expectedTruncated []bool) {
+	t.Helper()
+	if len(resp.Frames) != len(expectedRows) {
+		t.Fatalf("expected %d frames but %d", len(expectedRows), len(resp.Frames))
+	}
+	for i, frame := range resp.Frames {
+		if actual := frame.Rows(); actual != expectedRows[i] {
+			t.Errorf("expected rows of frame '%s' is %d but %d", frame.Name, expectedRows[i], actual)
+		}
+		if truncated := frame.Meta != nil && len(frame.Meta.Notices) == 1; truncated != expectedTruncated[i] {
+			t.Errorf("expected frame '%s' has the notice of truncation %v but %v", frame.Name, expectedTruncated[i], truncated)
+		}
+	}
+}
+
+func TestFetchWithDateRangeByPage(t *testing.T) {
+	query := &backend.DataQuery{
+		RefID: "A",
+	}
+	fromTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	toTime := time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC)
+	pages := map[string]fetchOncePage{
+		"": {
+			points:     map[string][]fiapmodel.Value{"id_a": valuesOf(1, "1", "2"), "id_b": valuesOf(1, "10")},
+			nextCursor: "cursor_1",
+		},
+		"cursor_1": {
+			points:     map[string][]fiapmodel.Value{"id_a": valuesOf(2, "3"), "id_b": valuesOf(2, "20", "30")},
+			nextCursor: "cursor_2",
+		},
+		"cursor_2": {
+			points:     map[string][]fiapmodel.Value{"id_b": valuesOf(3, "40")},
+			nextCursor: "",
+		},
+	}
+	pointIDs := []dsmodel.PointID{{Value: "id_a"}, {Value: "id_b", DataRange: dsmodel.Latest}}
+
+	t.Run("Normal", func(t *testing.T) {
+		t.Run("AllPages", func(t *testing.T) {
+			fetchClient := mockFetchClient{failLatest: true, failOldest: true, failDateRange: true, pages: pages}
+			cli := ClientImpl{Client: &fetchClient, Settings: &dsmodel.FiapDatasourceSettings{AcceptableSize: 3}}
+
+			resp := &backend.DataResponse{}
+			pointIDs := []dsmodel.PointID{{Value: "id_a"}, {Value: "id_b"}}
+			if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{}); err != nil {
+				t.Error(err)
+			}
+
+			if len(fetchClient.fetchOnceArguments) != 3 {
+				t.Fatalf("expected FetchOnce count is %d but %d", 3, len(fetchClient.fetchOnceArguments))
+			}
+			for i, expectedCursor := range []string{"", "cursor_1", "cursor_2"} {
+				if actual := fetchClient.fetchOnceArguments[i].option; actual.Cursor != expectedCursor || actual.AcceptableSize != 3 {
+					t.Errorf("expected option[%d] is cursor %s and size %d but %#v", i, expectedCursor, 3, actual)
+				}
+			}
+			if keys := fetchClient.fetchOnceArguments[0].keys; len(keys) != 2 {
+				t.Errorf("expected key length is %d but %d", 2, len(keys))
+			} else if keys[0].ID != "id_a" || keys[0].Gteq == nil || !keys[0].Gteq.Equal(fromTime) || keys[0].Lteq == nil || !keys[0].Lteq.Equal(toTime) || keys[0].MinMaxIndicator != fiapmodel.SelectTypeNone {
+				t.Errorf("unexpected key[0] %#v", keys[0])
+			}
+			checkFrame(resp, map[string][]fiapmodel.Value{"id_a": nil, "id_b": nil}, query, data.FieldTypeFloat64, func(message string) {
+				t.Error(message)
+			})
+			for i, expectedLen := range []int{3, 4} {
+				if actual := resp.Frames[i].Rows(); actual != expectedLen {
+					t.Errorf("expected rows of frame '%s' is %d but %d", resp.Frames[i].Name, expectedLen, actual)
+				}
+			}
+		})
+		t.Run("SelectType", func(t *testing.T) {
+			fetchClient := mockFetchClient{failLatest: true, failOldest: true, failDateRange: true, pages: map[string]fetchOncePage{
+				"": {points: map[string][]fiapmodel.Value{"id_a": valuesOf(1, "1"), "id_b": valuesOf(1, "10")}},
+			}}
+			cli := ClientImpl{Client: &fetchClient, Settings: &dsmodel.FiapDatasourceSettings{AcceptableSize: 3}}
+
+			resp := &backend.DataResponse{}
+			if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{}); err != nil {
+				t.Error(err)
+			}
+			if len(fetchClient.fetchOnceArguments) != 2 {
+				t.Fatalf("expected FetchOnce count is %d but %d", 2, len(fetchClient.fetchOnceArguments))
+			}
+			if actual := fetchClient.fetchOnceArguments[1].keys[0]; actual.ID != "id_b" || actual.MinMaxIndicator != fiapmodel.SelectTypeMaximum {
+				t.Errorf("expected key is %s with %s but %#v", "id_b", fiapmodel.SelectTypeMaximum, actual)
+			}
+		})
+		t.Run("Truncated", func(t *testing.T) {
+			fetchClient := mockFetchClient{failLatest: true, failOldest: true, failDateRange: true, pages: pages}
+			cli := ClientImpl{Client: &fetchClient, Settings: &dsmodel.FiapDatasourceSettings{AcceptableSize: 3, MaxRecords: 4}}
+
+			resp := &backend.DataResponse{}
+			pointIDs := []dsmodel.PointID{{Value: "id_a"}, {Value: "id_b"}}
+			if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{}); err != nil {
+				t.Error(err)
+			}
+
+			if len(fetchClient.fetchOnceArguments) != 2 {
+				t.Errorf("expected FetchOnce count is %d but %d", 2, len(fetchClient.fetchOnceArguments))
+			}
+			checkTruncated(t, resp, []int{3, 1}, []bool{false, true})
+		})
+		t.Run("TruncatedOverGroups", func(t *testing.T) {
+			// the mock returns both points for each group, and the second group finds the budget spent by the first one.
+			fetchClient := mockFetchClient{failOldest: true, results: &fetchClientResults{
+				pointSets: map[string]fiapmodel.ProcessedPointSet{},
+				points:    map[string][]fiapmodel.Value{"id_a": valuesOf(1, "1", "2", "3"), "id_b": valuesOf(1, "10", "20")},
+			}}
+			cli := ClientImpl{Client: &fetchClient, Settings: &dsmodel.FiapDatasourceSettings{MaxRecords: 3}}
+
+			resp := &backend.DataResponse{}
+			if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{}); err != nil {
+				t.Error(err)
+			}
+			checkTruncated(t, resp, []int{3, 0}, []bool{false, true})
+		})
+		t.Run("TruncatedWithoutPaging", func(t *testing.T) {
+			cli, _ := newDateRangeClient(map[string][]fiapmodel.Value{"id_a": valuesOf(1, "1", "2", "3"), "id_b": valuesOf(1, "10", "20")}, &dsmodel.FiapDatasourceSettings{MaxRecords: 4})
+
+			resp := &backend.DataResponse{}
+			pointIDs := []dsmodel.PointID{{Value: "id_a"}, {Value: "id_b"}}
+			if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{}); err != nil {
+				t.Error(err)
+			}
+			checkTruncated(t, resp, []int{3, 1}, []bool{false, true})
+		})
+	})
+	t.Run("Error", func(t *testing.T) {
+		fetchClient := mockFetchClient{failLatest: true, failOldest: true, failDateRange: true, pages: map[string]fetchOncePage{
+			"": {points: map[string][]fiapmodel.Value{"id_a": valuesOf(1, "1")}, nextCursor: "unknown"},
+		}}
+		cli := ClientImpl{Client: &fetchClient, Settings: &dsmodel.FiapDatasourceSettings{AcceptableSize: 3}}
+
+		resp := &backend.DataResponse{}
+		err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, []dsmodel.PointID{{Value: "id_a"}}, query, &dsmodel.QueryOptions{})
+		if expectedErr := "fetch page 2"; err == nil {
+			t.Errorf("expected error is %s but nil", expectedErr)
+		} else if !strings.Contains(err.Error(), expectedErr) {
+			t.Errorf("expected error is %s but %s", expectedErr, err.Error())
+		}
+	})
+}
+
 func checkFrame(resp *backend.DataResponse, points map[string]([]fiapmodel.Value), query *backend.DataQuery, expectedType data.FieldType, cb func(string)) {
 	for pointID := range points {
 		expectedFrameName := query.RefID + ":" + pointID
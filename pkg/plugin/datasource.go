@@ -4,33 +4,60 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/sios/fiap/pkg/model"
 
+	"github.com/cockroachdb/errors"
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 )
 
 // Make sure Datasource implements required interfaces. This is important to do
 // since otherwise we will only get a not implemented error response from plugin in
 // runtime. In this example datasource instance implements backend.QueryDataHandler,
-// backend.CheckHealthHandler interfaces. Plugin should not implement all these
+// backend.CheckHealthHandler, backend.CallResourceHandler interfaces. Plugin should not implement all these
 // interfaces - only those which are required for a particular task.
 var (
 	_ backend.QueryDataHandler      = (*Datasource)(nil)
 	_ backend.CheckHealthHandler    = (*Datasource)(nil)
+	_ backend.CallResourceHandler   = (*Datasource)(nil)
 	_ instancemgmt.InstanceDisposer = (*Datasource)(nil)
 )
 
 var createClient model.FiapApiClientCreator = CreateFiapApiClient
 
+// timeNow returns the current time used for relative time expressions.
+var timeNow = time.Now
+
 // NewDatasource creates a new datasource instance.
 func NewDatasource(_ context.Context, settings backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
 	var ds *Datasource = &Datasource{}
 	if err := json.Unmarshal(settings.JSONData, &(ds.Settings)); err != nil {
 		return nil, err
 	}
+	ds.Settings.Secure = model.LoadSecureSettings(settings.DecryptedSecureJSONData)
+	if err := ds.Settings.ValidateAuth(); err != nil {
+		return nil, errors.Wrap(err, "authentication")
+	}
+	if mapping := ds.Settings.DefaultValueMapping; mapping != nil {
+		if err := mapping.Validate(); err != nil {
+			return nil, errors.Wrap(err, "default value mapping")
+		}
+	}
+	if ds.Settings.MaxRecords < 0 {
+		return nil, errors.Newf("max records must not be negative but %d", ds.Settings.MaxRecords)
+	}
+	if err := ds.Settings.Retry.Validate(); err != nil {
+		return nil, errors.Wrap(err, "retry policy")
+	}
+	for pointID, unit := range ds.Settings.PointUnits {
+		if err := unit.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "unit of point id '%s'", pointID)
+		}
+	}
 	if cli, err := createClient(&(ds.Settings)); err != nil {
 		return nil, err
 	} else {
@@ -44,6 +71,9 @@ func NewDatasource(_ context.Context, settings backend.DataSourceInstanceSetting
 type Datasource struct {
 	Settings model.FiapDatasourceSettings
 	Client   model.FiapApiClient
+
+	// pointTree caches the point sets browsed by the query editor.
+	pointTree pointTreeCache
 }
 
 // Dispose here tells plugin SDK that plugin wants to clean up resources when a new instance
@@ -51,6 +81,9 @@ type Datasource struct {
 // be disposed and a new one will be created using NewSampleDatasource factory function.
 func (d *Datasource) Dispose() {
 	// Clean up datasource instance resources.
+	if closer, ok := d.Client.(io.Closer); ok {
+		closer.Close()
+	}
 }
 
 // QueryData handles multiple queries and returns multiple responses.
@@ -97,38 +130,238 @@ func (d *Datasource) query(ctx context.Context, _ backend.PluginContext, query *
 		ctxLogger.Error("Error parse server timezone in settings", "timezone", d.Settings.ServerTimezone, "error", err)
 		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("server timezone parse: %v", err.Error()))
 	}
+	if qm.Timezone != "" {
+		if tz, err := model.LoadLocation(qm.Timezone); err == nil {
+			serverTimezone = tz
+		} else {
+			ctxLogger.Error("Error parse timezone in query", "timezone", qm.Timezone, "error", err)
+			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("timezone parse: %v", err.Error()))
+		}
+	}
+	var timeShift model.TimeShift
+	if shift, err := model.ParseTimeShift(qm.TimeShift); err == nil {
+		timeShift = shift
+	} else {
+		ctxLogger.Error("Error parse time shift in query", "timeShift", qm.TimeShift, "error", err)
+		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("time shift parse: %v", err.Error()))
+	}
+	if err := qm.QueryOptions.Validate(); err != nil {
+		ctxLogger.Error("Error validate options in query", "options", qm.QueryOptions, "error", err)
+		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("query options parse: %v", err.Error()))
+	}
+	var aggregationInterval model.Interval
+	if qm.AggregationInterval == "" {
+		aggregationInterval = model.IntervalOf(query.Interval)
+	} else if interval, err := model.ParseInterval(qm.AggregationInterval); err == nil {
+		aggregationInterval = interval
+	} else {
+		ctxLogger.Error("Error parse aggregation interval in query", "interval", qm.AggregationInterval, "error", err)
+		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("aggregation interval parse: %v", err.Error()))
+	}
+	if qm.Aggregation != "" {
+		if err := qm.Aggregation.Validate(); err != nil {
+			ctxLogger.Error("Error validate aggregation in query", "aggregation", qm.Aggregation, "error", err)
+			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("aggregation parse: %v", err.Error()))
+		}
+	}
+	var transform *counterTransform
+	if qm.Transform != "" {
+		if err := qm.Transform.Validate(); err != nil {
+			ctxLogger.Error("Error validate transform in query", "transform", qm.Transform, "error", err)
+			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("transform parse: %v", err.Error()))
+		}
+		rateUnit, err := model.ParseRateUnit(qm.RateUnit)
+		if err != nil {
+			ctxLogger.Error("Error parse rate unit in query", "rateUnit", qm.RateUnit, "error", err)
+			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("rate unit parse: %v", err.Error()))
+		}
+		if qm.CounterMaxValue != nil && *qm.CounterMaxValue <= 0 {
+			ctxLogger.Error("Error validate counter max value in query", "counterMaxValue", *qm.CounterMaxValue)
+			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("counter max value must be positive: %v", *qm.CounterMaxValue))
+		}
+		transform = &counterTransform{
+			transform: qm.Transform,
+			rateUnit:  rateUnit,
+			interval:  aggregationInterval,
+			location:  serverTimezone,
+			maxValue:  qm.CounterMaxValue,
+		}
+	}
+	var fillThreshold *model.Interval
+	if qm.Fill != "" {
+		if err := qm.Fill.Validate(); err != nil {
+			ctxLogger.Error("Error validate fill mode in query", "fill", qm.Fill, "error", err)
+			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("fill parse: %v", err.Error()))
+		}
+		if qm.FillThreshold != "" {
+			threshold, err := model.ParseInterval(qm.FillThreshold)
+			if err != nil {
+				ctxLogger.Error("Error parse fill threshold in query", "fillThreshold", qm.FillThreshold, "error", err)
+				return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("fill threshold parse: %v", err.Error()))
+			}
+			fillThreshold = &threshold
+		}
+	}
+	if err := qm.FrameLayout.Validate(); err != nil {
+		ctxLogger.Error("Error validate frame layout in query", "frameLayout", qm.FrameLayout, "error", err)
+		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("frame layout parse: %v", err.Error()))
+	}
+	var joinRounding *model.Interval
+	if qm.JoinRounding != "" {
+		if rounding, err := model.ParseInterval(qm.JoinRounding); err == nil {
+			joinRounding = &rounding
+		} else {
+			ctxLogger.Error("Error parse join rounding in query", "joinRounding", qm.JoinRounding, "error", err)
+			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("join rounding parse: %v", err.Error()))
+		}
+	}
+	if qm.Downsampling != "" {
+		if err := qm.Downsampling.Validate(); err != nil {
+			ctxLogger.Error("Error validate downsampling in query", "downsampling", qm.Downsampling, "error", err)
+			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("downsampling parse: %v", err.Error()))
+		}
+	}
+	timeContext := &model.TimeContext{Location: serverTimezone, Now: timeNow(), DashboardRange: query.TimeRange}
 	var fromTime *time.Time
-	if qm.StartTime.LinkDashboard {
-		dt := query.TimeRange.From.In(serverTimezone)
-		fromTime = &dt
-	} else if dt, err := qm.StartTime.GetTime(d.Settings.ServerTimezone); err == nil {
+	if dt, err := resolveTime(&qm.StartTime, query.TimeRange.From, timeContext, false); err == nil {
 		fromTime = dt
 	} else {
 		ctxLogger.Error("Error parse start time in query", "time", qm.StartTime.RawTime, "error", err)
 		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("start time parse: %v", err.Error()))
 	}
 	var toTime *time.Time
-	if qm.EndTime.LinkDashboard {
-		dt := query.TimeRange.To.In(serverTimezone)
-		toTime = &dt
-	} else if dt, err := qm.EndTime.GetTime(d.Settings.ServerTimezone); err == nil {
+	if dt, err := resolveTime(&qm.EndTime, query.TimeRange.To, timeContext, true); err == nil {
 		toTime = dt
 	} else {
 		ctxLogger.Error("Error parse end time in query", "time", qm.EndTime.RawTime, "error", err)
 		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("end time parse: %v", err.Error()))
 	}
 
-	ctxLogger.Debug("Start fetch point data", "connectionURL", d.Settings.Url, "dataRange", qm.DataRange, "fromTime", fromTime, "toTime", toTime, "pointIDs", qm.PointIDs)
-	err := d.Client.FetchWithDateRange(&response, qm.DataRange, fromTime, toTime, qm.PointIDs, query)
+	// interpolate template variables, expanding multi-value variables into multiple point IDs.
+	qm.PointIDs = interpolatePointIDs(qm.PointIDs, qm.ScopedVars)
+
+	// validate the value mapping and resolve the time range overridden by each point.
+	for i := range qm.PointIDs {
+		pointID := &qm.PointIDs[i]
+		if pointID.ValueMapping != nil {
+			if err := pointID.ValueMapping.Validate(); err != nil {
+				ctxLogger.Error("Error validate value mapping in point", "pointID", pointID.Value, "error", err)
+				return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("value mapping of point id '%s': %v", pointID.Value, err.Error()))
+			}
+		}
+		if pointID.StartTime != nil {
+			if dt, err := resolveTime(pointID.StartTime, query.TimeRange.From, timeContext, false); err == nil {
+				pointID.FromTime = dt
+			} else {
+				ctxLogger.Error("Error parse start time in point", "pointID", pointID.Value, "time", pointID.StartTime.RawTime, "error", err)
+				return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("start time parse of point id '%s': %v", pointID.Value, err.Error()))
+			}
+		}
+		if pointID.EndTime != nil {
+			if dt, err := resolveTime(pointID.EndTime, query.TimeRange.To, timeContext, true); err == nil {
+				pointID.ToTime = dt
+			} else {
+				ctxLogger.Error("Error parse end time in point", "pointID", pointID.Value, "time", pointID.EndTime.RawTime, "error", err)
+				return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("end time parse of point id '%s': %v", pointID.Value, err.Error()))
+			}
+		}
+	}
+
+	// move the time range into the past by the time shift.
+	if len(timeShift) > 0 {
+		fromTime, toTime = shiftTimeBack(timeShift, fromTime), shiftTimeBack(timeShift, toTime)
+		for i := range qm.PointIDs {
+			qm.PointIDs[i].FromTime, qm.PointIDs[i].ToTime = shiftTimeBack(timeShift, qm.PointIDs[i].FromTime), shiftTimeBack(timeShift, qm.PointIDs[i].ToTime)
+		}
+	}
+
+	ctxLogger.Debug("Start fetch point data", "connectionURL", d.Settings.Url, "dataRange", qm.DataRange, "fromTime", fromTime, "toTime", toTime, "pointIDs", qm.PointIDs, "options", qm.QueryOptions)
+	err := d.Client.FetchWithDateRange(ctx, &response, qm.DataRange, fromTime, toTime, qm.PointIDs, query, &qm.QueryOptions)
 	if err != nil {
 		ctxLogger.Error("Error fetch point data", "json", query.JSON, "error", err)
 		return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("fiap fetch: %v", err.Error()))
 	}
 
+	// convert the values of counters into the differences.
+	if transform != nil {
+		if err := transformFrames(response.Frames, transform); err != nil {
+			ctxLogger.Error("Error transform point data", "transform", qm.Transform, "error", err)
+			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("transform: %v", err.Error()))
+		}
+	}
+
+	// aggregate the values into buckets aligned to the server timezone.
+	if qm.Aggregation != "" {
+		if err := aggregateFrames(response.Frames, qm.Aggregation, aggregationInterval, serverTimezone); err != nil {
+			ctxLogger.Error("Error aggregate point data", "aggregation", qm.Aggregation, "error", err)
+			return backend.ErrDataResponse(backend.StatusBadRequest, fmt.Sprintf("aggregation: %v", err.Error()))
+		}
+	}
+
+	// fill the gaps where samples are dropped.
+	if qm.Fill != "" {
+		fillFrames(response.Frames, qm.Fill, fillThreshold)
+	}
+
+	// reduce the values which are more than the panel can show.
+	if qm.Downsampling != "" {
+		downsampleFrames(response.Frames, qm.Downsampling, query.MaxDataPoints)
+	}
+
+	// line the shifted data up with the time range of the dashboard.
+	if len(timeShift) > 0 {
+		shiftFrameTimes(response.Frames, timeShift.Forward)
+	}
+
+	// reshape the frames of the points.
+	if qm.FrameLayout == model.WideFrameLayout && len(response.Frames) > 0 {
+		response.Frames = data.Frames{joinFrames(response.Frames, query.RefID, joinRounding, serverTimezone)}
+	} else if qm.FrameLayout == model.LongFrameLayout && len(response.Frames) > 0 {
+		response.Frames = data.Frames{longFrameOf(response.Frames, query.RefID)}
+	}
+
 	ctxLogger.Debug("Finish handle query normally", "response", response)
 	return response
 }
 
+func shiftTimeBack(timeShift model.TimeShift, dt *time.Time) *time.Time {
+	if dt == nil {
+		return nil
+	}
+	shifted := timeShift.Back(*dt)
+	return &shifted
+}
+
+// shiftFrameTimes replaces all values of time fields in the frames with the result of shift.
+func shiftFrameTimes(frames data.Frames, shift func(time.Time) time.Time) {
+	for _, frame := range frames {
+		for _, field := range frame.Fields {
+			switch field.Type() {
+			case data.FieldTypeTime:
+				for i := 0; i < field.Len(); i++ {
+					field.Set(i, shift(field.At(i).(time.Time)))
+				}
+			case data.FieldTypeNullableTime:
+				for i := 0; i < field.Len(); i++ {
+					if dt := field.At(i).(*time.Time); dt != nil {
+						shifted := shift(*dt)
+						field.Set(i, &shifted)
+					}
+				}
+			}
+		}
+	}
+}
+
+// resolveTime returns the time of the dashboard when the time is linked to it, otherwise the time entered in the query.
+func resolveTime(linkedTime *model.LinkedTime, dashboardTime time.Time, timeContext *model.TimeContext, roundUp bool) (*time.Time, error) {
+	if linkedTime.LinkDashboard {
+		dt := dashboardTime.In(timeContext.Location)
+		return &dt, nil
+	}
+	return linkedTime.GetTime(timeContext, roundUp)
+}
+
 // CheckHealth handles health checks sent from Grafana to the plugin.
 // The main use case for these health checks is the test button on the
 // datasource configuration page which allows users to verify that
@@ -137,7 +370,7 @@ func (d *Datasource) CheckHealth(ctx context.Context, _ *backend.CheckHealthRequ
 	ctxLogger := backend.Logger.FromContext(ctx)
 	ctxLogger.Debug("Start CheckHealth in fiap datasource")
 
-	result, err := d.Client.CheckHealth()
+	result, err := d.Client.CheckHealth(ctx)
 	if err != nil {
 		ctxLogger.Error("Unexpected error in health check", "error", err)
 		return nil, err
@@ -3,6 +3,7 @@ package plugin
 import (
 	"context"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -23,6 +24,7 @@ type MockClient struct {
 
 	checkHealthFunc        func() (*backend.CheckHealthResult, error)
 	fetchWithDateRangeFunc func(resp *backend.DataResponse, dataRange model.DataRangeType, fromTime *time.Time, toTime *time.Time, pointIDs []model.PointID, query *backend.DataQuery) error
+	fetchChildrenFunc      func(pointSetID string) ([]string, []string, error)
 }
 
 type fetchFuncArguments struct {
@@ -30,8 +32,12 @@ type fetchFuncArguments struct {
 	fromTime  *time.Time
 	toTime    *time.Time
 	pointIDs  []model.PointID
+	options   *model.QueryOptions
 }
 
+// emptyPointID is the point for which the mock client returns no values.
+const emptyPointID = "id_empty"
+
 func createDefaultMockClient(settings *model.FiapDatasourceSettings) (model.FiapApiClient, error) {
 	return &MockClient{
 		checkHealthFunc: func() (*backend.CheckHealthResult, error) {
@@ -47,6 +53,16 @@ func createDefaultMockClient(settings *model.FiapDatasourceSettings) (model.Fiap
 				// https://grafana.com/developers/plugin-tools/introduction/data-frames
 				frame := data.NewFrame(fmt.Sprintf("%s:%s", query.RefID, pointID.Value))
 
+				// the point without samples has an empty string field like the ones of the client.
+				if pointID.Value == emptyPointID {
+					frame.Fields = append(frame.Fields,
+						data.NewField("time", nil, []time.Time{}),
+						data.NewField(pointID.Value, nil, []string{}),
+					)
+					resp.Frames = append(resp.Frames, frame)
+					continue
+				}
+
 				// add fields.
 				frame.Fields = append(frame.Fields,
 					data.NewField("time", nil, []time.Time{*fromTime, *toTime}),
@@ -61,20 +77,28 @@ func createDefaultMockClient(settings *model.FiapDatasourceSettings) (model.Fiap
 	}, nil
 }
 
-func (cli *MockClient) CheckHealth() (*backend.CheckHealthResult, error) {
+func (cli *MockClient) CheckHealth(_ context.Context) (*backend.CheckHealthResult, error) {
 	return cli.checkHealthFunc()
 }
 
-func (cli *MockClient) FetchWithDateRange(resp *backend.DataResponse, dataRange model.DataRangeType, fromTime *time.Time, toTime *time.Time, pointIDs []model.PointID, query *backend.DataQuery) error {
+func (cli *MockClient) FetchWithDateRange(_ context.Context, resp *backend.DataResponse, dataRange model.DataRangeType, fromTime *time.Time, toTime *time.Time, pointIDs []model.PointID, query *backend.DataQuery, options *model.QueryOptions) error {
 	cli.actualArguments = &fetchFuncArguments{
 		dataRange: dataRange,
 		fromTime:  fromTime,
 		toTime:    toTime,
 		pointIDs:  pointIDs,
+		options:   options,
 	}
 	return cli.fetchWithDateRangeFunc(resp, dataRange, fromTime, toTime, pointIDs, query)
 }
 
+func (cli *MockClient) FetchChildren(_ context.Context, pointSetID string) ([]string, []string, error) {
+	if cli.fetchChildrenFunc == nil {
+		return nil, nil, errors.New("not expected to call this function")
+	}
+	return cli.fetchChildrenFunc(pointSetID)
+}
+
 func TestNewDatasource(t *testing.T) {
 	originalCreateClient := createClient
 	t.Run("Normal", func(t *testing.T) {
@@ -97,6 +121,21 @@ func TestNewDatasource(t *testing.T) {
 			}
 		}
 	})
+	t.Run("CustomHeaders", func(t *testing.T) {
+		createClient = createDefaultMockClient
+
+		inst, err := NewDatasource(context.Background(), backend.DataSourceInstanceSettings{
+			JSONData:                []byte(`{"url":"http://test.url:12345","httpHeaderName1":"X-Tenant-Id","httpHeaderName3":"X-Site","httpHeaderName4":""}`),
+			DecryptedSecureJSONData: map[string]string{"httpHeaderValue1": "plant1", "httpHeaderValue3": "tokyo", "httpHeaderValue4": "unused"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]string{"X-Tenant-Id": "plant1", "X-Site": "tokyo"}
+		if actual := inst.(*Datasource).Settings.CustomHeaders(); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("expected custom headers are %v but %v", expected, actual)
+		}
+	})
 	t.Run("Error", func(t *testing.T) {
 		t.Run("InvalidSettings", func(t *testing.T) {
 			createClient = createDefaultMockClient
@@ -110,6 +149,72 @@ func TestNewDatasource(t *testing.T) {
 				t.Error("NewDatasource must return an error")
 			}
 		})
+		t.Run("InvalidDefaultValueMapping", func(t *testing.T) {
+			createClient = createDefaultMockClient
+
+			inst, err := NewDatasource(context.TODO(), backend.DataSourceInstanceSettings{
+				JSONData: []byte(`{"url":"http://test.url:12345","default_value_mapping":{"type":"enum"}}`),
+			})
+			if inst != nil {
+				t.Error("NewDatasource must not return new datasource")
+			} else if err == nil {
+				t.Error("NewDatasource must return an error")
+			}
+		})
+		t.Run("InvalidPointUnit", func(t *testing.T) {
+			createClient = createDefaultMockClient
+
+			inst, err := NewDatasource(context.TODO(), backend.DataSourceInstanceSettings{
+				JSONData: []byte(`{"url":"http://test.url:12345","point_units":{"id_a":{"unit":"W","display_unit":"kPa"}}}`),
+			})
+			if inst != nil {
+				t.Error("NewDatasource must not return new datasource")
+			} else if err == nil {
+				t.Error("NewDatasource must return an error")
+			}
+		})
+		t.Run("InvalidHeaderName", func(t *testing.T) {
+			createClient = createDefaultMockClient
+
+			inst, err := NewDatasource(context.TODO(), backend.DataSourceInstanceSettings{
+				JSONData: []byte(`{"url":"http://test.url:12345","httpHeaderName1":1}`),
+			})
+			if inst != nil {
+				t.Error("NewDatasource must not return new datasource")
+			} else if err == nil {
+				t.Error("NewDatasource must return an error")
+			}
+		})
+		t.Run("InvalidMaxRecords", func(t *testing.T) {
+			createClient = createDefaultMockClient
+
+			inst, err := NewDatasource(context.TODO(), backend.DataSourceInstanceSettings{
+				JSONData: []byte(`{"url":"http://test.url:12345","max_records":-1}`),
+			})
+			if inst != nil {
+				t.Error("NewDatasource must not return new datasource")
+			} else if err == nil {
+				t.Error("NewDatasource must return an error")
+			}
+		})
+		t.Run("InvalidAuth", func(t *testing.T) {
+			createClient = createDefaultMockClient
+
+			for _, jsonData := range []string{
+				`{"url":"http://test.url:12345","auth_type":"basic"}`,
+				`{"url":"http://test.url:12345","auth_type":"bearer"}`,
+				`{"url":"http://test.url:12345","auth_type":"digest"}`,
+			} {
+				inst, err := NewDatasource(context.TODO(), backend.DataSourceInstanceSettings{
+					JSONData: []byte(jsonData),
+				})
+				if inst != nil {
+					t.Errorf("NewDatasource must not return new datasource of %s", jsonData)
+				} else if err == nil {
+					t.Errorf("NewDatasource must return an error of %s", jsonData)
+				}
+			}
+		})
 		t.Run("ClientCreation", func(t *testing.T) {
 			createClient = func(_ *model.FiapDatasourceSettings) (model.FiapApiClient, error) {
 				return nil, errors.New("test client creation error")
@@ -308,6 +413,95 @@ func TestQueryData(t *testing.T) {
 				}
 			}
 		})
+		t.Run("PointOverrideQuery", func(t *testing.T) {
+			resp, err := ds.QueryData(
+				context.Background(),
+				&backend.QueryDataRequest{
+					Queries: []backend.DataQuery{
+						{
+							RefID: "A",
+							JSON:  []byte(`{"point_ids":[{"point_id":"id_a"},{"point_id":"id_b","data_range":"latest","start_time":{"time":"2024-06-01 00:00:00","link_dashboard":false},"end_time":{"time":"","link_dashboard":true},"alias":"meter"}],"data_range":"period","start_time":{"time":"","link_dashboard":true},"end_time":{"time":"","link_dashboard":true}}`),
+							TimeRange: backend.TimeRange{
+								From: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+								To:   time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC),
+							},
+						},
+					},
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if respA, ok := resp.Responses["A"]; !ok {
+				t.Errorf("QueryData must return response of RefID '%s'", "A")
+			} else if respA.Error != nil {
+				t.Errorf("failed query of RefID '%s': %s", "A", respA.Error.Error())
+			}
+
+			if cli, ok := ds.Client.(*MockClient); !ok {
+				t.Fatal("client is not mock")
+			} else {
+				if len(cli.actualArguments.pointIDs) != 2 {
+					t.Fatalf("expected pointIDs' length is %d but %d", 2, len(cli.actualArguments.pointIDs))
+				}
+				if pointID := cli.actualArguments.pointIDs[0]; pointID.StartTime != nil || pointID.EndTime != nil {
+					t.Errorf("expected pointID[0] does not override time range but %v", pointID)
+				}
+				pointID := cli.actualArguments.pointIDs[1]
+				if pointID.DataRange != "latest" {
+					t.Errorf("expected datarange of pointID[1] is %s but %s", "latest", pointID.DataRange)
+				}
+				if pointID.Alias != "meter" {
+					t.Errorf("expected alias of pointID[1] is %s but %s", "meter", pointID.Alias)
+				}
+				if pointID.FromTime == nil {
+					t.Errorf("expected fromTime of pointID[1] is %s but nil", "2024-06-01 00:00:00 +00:00")
+				} else if !pointID.FromTime.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)) {
+					t.Errorf("expected fromTime of pointID[1] is %s but %s", "2024-06-01 00:00:00 +00:00", pointID.FromTime.Format("2006-01-02 15:04:05 -07:00"))
+				}
+				if pointID.ToTime == nil {
+					t.Errorf("expected toTime of pointID[1] is %s but nil", "2024-03-31 23:59:59 +00:00")
+				} else if !pointID.ToTime.Equal(time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC)) {
+					t.Errorf("expected toTime of pointID[1] is %s but %s", "2024-03-31 23:59:59 +00:00", pointID.ToTime.Format("2006-01-02 15:04:05 -07:00"))
+				}
+			}
+		})
+		t.Run("TemplateVariableQuery", func(t *testing.T) {
+			resp, err := ds.QueryData(
+				context.Background(),
+				&backend.QueryDataRequest{
+					Queries: []backend.DataQuery{
+						{
+							RefID: "A",
+							JSON:  []byte(`{"point_ids":[{"point_id":"$building/$sensors"}],"data_range":"period","start_time":{"time":"","link_dashboard":true},"end_time":{"time":"","link_dashboard":true},"scoped_vars":{"building":{"text":"b1","value":"b1"},"sensors":{"text":"temp + hum","value":["temp","hum"]}}}`),
+							TimeRange: backend.TimeRange{
+								From: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+								To:   time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC),
+							},
+						},
+					},
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if respA, ok := resp.Responses["A"]; !ok {
+				t.Errorf("QueryData must return response of RefID '%s'", "A")
+			} else if respA.Error != nil {
+				t.Errorf("failed query of RefID '%s': %s", "A", respA.Error.Error())
+			}
+
+			cli := ds.Client.(*MockClient)
+			expectedPointIDs := []string{"b1/temp", "b1/hum"}
+			if len(cli.actualArguments.pointIDs) != len(expectedPointIDs) {
+				t.Fatalf("expected pointIDs' length is %d but %d", len(expectedPointIDs), len(cli.actualArguments.pointIDs))
+			}
+			for i := range expectedPointIDs {
+				if pointID := cli.actualArguments.pointIDs[i].Value; pointID != expectedPointIDs[i] {
+					t.Errorf("expected pointID[%d] is %s but %s", i, expectedPointIDs[i], pointID)
+				}
+			}
+		})
 		t.Run("MultipleQuery", func(t *testing.T) {
 			resp, err := ds.QueryData(
 				context.Background(),
@@ -437,6 +631,52 @@ func TestQueryData(t *testing.T) {
 				}
 			})
 		})
+		t.Run("InvalidPointTime", func(t *testing.T) {
+			resp, err := ds.QueryData(
+				context.Background(),
+				&backend.QueryDataRequest{
+					Queries: []backend.DataQuery{
+						{
+							RefID: "A",
+							JSON:  []byte(`{"point_ids":[{"point_id":"id_b","start_time":{"time":"2024-06-01","link_dashboard":false}}],"data_range":"latest","start_time":{"time":"2024-06-01 00:00:00","link_dashboard":false},"end_time":{"time":"2024-06-30 23:59:59","link_dashboard":false}}`),
+							TimeRange: backend.TimeRange{
+								From: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+								To:   time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC),
+							},
+						},
+					},
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if respA, ok := resp.Responses["A"]; !ok {
+				t.Errorf("QueryData must return response of RefID '%s'", "A")
+			} else if !strings.Contains(respA.Error.Error(), "start time parse of point id 'id_b'") {
+				t.Errorf("expected error is %s but %s", "start time parse of point id 'id_b'", respA.Error.Error())
+			}
+		})
+		t.Run("InvalidValueMapping", func(t *testing.T) {
+			resp, err := ds.QueryData(
+				context.Background(),
+				&backend.QueryDataRequest{
+					Queries: []backend.DataQuery{
+						{
+							RefID: "A",
+							JSON:  []byte(`{"point_ids":[{"point_id":"id_b","value_mapping":{"type":"color"}}],"data_range":"latest","start_time":{"time":"2024-06-01 00:00:00","link_dashboard":false},"end_time":{"time":"2024-06-30 23:59:59","link_dashboard":false}}`),
+						},
+					},
+				},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if respA, ok := resp.Responses["A"]; !ok {
+				t.Errorf("QueryData must return response of RefID '%s'", "A")
+			} else if !strings.Contains(respA.Error.Error(), "value mapping of point id 'id_b'") {
+				t.Errorf("expected error is %s but %s", "value mapping of point id 'id_b'", respA.Error.Error())
+			}
+		})
 		t.Run("FetchFailed", func(t *testing.T) {
 			ds := Datasource{Client: &MockClient{
 				checkHealthFunc: func() (*backend.CheckHealthResult, error) {
@@ -658,6 +898,67 @@ func TestQueryDataWithServerTz(t *testing.T) {
 			}
 		})
 	})
+	t.Run("IanaTimezone", func(t *testing.T) {
+		originalTimeNow := timeNow
+		timeNow = func() time.Time {
+			return time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+		}
+		defer func() { timeNow = originalTimeNow }()
+
+		ds := Datasource{Client: &MockClient{
+			checkHealthFunc: func() (*backend.CheckHealthResult, error) {
+				return nil, errors.New("not expected to call this function")
+			},
+			fetchWithDateRangeFunc: func(_ *backend.DataResponse, _ model.DataRangeType, _ *time.Time, _ *time.Time, _ []model.PointID, _ *backend.DataQuery) error {
+				return nil
+			},
+		}, Settings: model.FiapDatasourceSettings{
+			Url:            "http://test.url:12345",
+			ServerTimezone: "Europe/Berlin",
+		}}
+		// daylight saving time in Europe/Berlin starts at 2024-03-31 02:00 +01:00.
+		cases := []struct {
+			name               string
+			startTime, endTime string
+			expectedFromTime   time.Time
+			expectedToTime     time.Time
+		}{
+			{"FixedTime", "2024-03-31 00:00:00", "2024-03-31 12:00:00", time.Date(2024, 3, 30, 23, 0, 0, 0, time.UTC), time.Date(2024, 3, 31, 10, 0, 0, 0, time.UTC)},
+			{"RelativeTime", "now/d", "now/d", time.Date(2024, 3, 30, 23, 0, 0, 0, time.UTC), time.Date(2024, 3, 31, 21, 59, 59, 0, time.UTC)},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				resp, err := ds.QueryData(
+					context.Background(),
+					&backend.QueryDataRequest{
+						Queries: []backend.DataQuery{
+							{
+								RefID: "A",
+								JSON:  []byte(fmt.Sprintf(`{"point_ids":[{"point_id":"id_a"}],"data_range":"period","start_time":{"time":"%s","link_dashboard":false},"end_time":{"time":"%s","link_dashboard":false}}`, c.startTime, c.endTime)),
+							},
+						},
+					},
+				)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if respA := resp.Responses["A"]; respA.Error != nil {
+					t.Fatalf("failed query of RefID '%s': %s", "A", respA.Error.Error())
+				}
+				cli := ds.Client.(*MockClient)
+				if cli.actualArguments.fromTime == nil {
+					t.Errorf("expected fromTime is %s but nil", c.expectedFromTime.Format("2006-01-02 15:04:05 -07:00"))
+				} else if !cli.actualArguments.fromTime.Equal(c.expectedFromTime) {
+					t.Errorf("expected fromTime is %s but %s", c.expectedFromTime.Format("2006-01-02 15:04:05 -07:00"), cli.actualArguments.fromTime.Format("2006-01-02 15:04:05 -07:00"))
+				}
+				if cli.actualArguments.toTime == nil {
+					t.Errorf("expected toTime is %s but nil", c.expectedToTime.Format("2006-01-02 15:04:05 -07:00"))
+				} else if !cli.actualArguments.toTime.Equal(c.expectedToTime) {
+					t.Errorf("expected toTime is %s but %s", c.expectedToTime.Format("2006-01-02 15:04:05 -07:00"), cli.actualArguments.toTime.Format("2006-01-02 15:04:05 -07:00"))
+				}
+			})
+		}
+	})
 	t.Run("Error", func(t *testing.T) {
 		t.Run("InvalidTimezone", func(t *testing.T) {
 			ds := Datasource{Client: &MockClient{
@@ -716,6 +1017,350 @@ func TestQueryDataWithServerTz(t *testing.T) {
 	})
 }
 
+func TestQueryDataWithRelativeTime(t *testing.T) {
+	originalTimeNow := timeNow
+	timeNow = func() time.Time {
+		return time.Date(2024, 6, 15, 10, 30, 0, 0, time.UTC)
+	}
+	defer func() { timeNow = originalTimeNow }()
+
+	ds := Datasource{Client: &MockClient{
+		checkHealthFunc: func() (*backend.CheckHealthResult, error) {
+			return nil, errors.New("not expected to call this function")
+		},
+		fetchWithDateRangeFunc: func(_ *backend.DataResponse, _ model.DataRangeType, _ *time.Time, _ *time.Time, _ []model.PointID, _ *backend.DataQuery) error {
+			return nil
+		},
+	}, Settings: model.FiapDatasourceSettings{
+		Url:            "http://test.url:12345",
+		ServerTimezone: "+09:00",
+	}}
+	timezone := time.FixedZone("+09:00", 9*60*60)
+	queryWithTime := func(startTime, endTime string) backend.DataResponse {
+		resp, err := ds.QueryData(
+			context.Background(),
+			&backend.QueryDataRequest{
+				Queries: []backend.DataQuery{
+					{
+						RefID: "A",
+						JSON:  []byte(fmt.Sprintf(`{"point_ids":[{"point_id":"id_a"}],"data_range":"period","start_time":{"time":"%s","link_dashboard":false},"end_time":{"time":"%s","link_dashboard":false}}`, startTime, endTime)),
+						TimeRange: backend.TimeRange{
+							From: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+							To:   time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC),
+						},
+					},
+				},
+			},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Responses["A"]
+	}
+	t.Run("Normal", func(t *testing.T) {
+		cases := []struct {
+			name               string
+			startTime, endTime string
+			expectedFromTime   time.Time
+			expectedToTime     time.Time
+		}{
+			{"Offset", "now-7d", "now", time.Date(2024, 6, 8, 19, 30, 0, 0, timezone), time.Date(2024, 6, 15, 19, 30, 0, 0, timezone)},
+			{"RoundDay", "now/d", "now/d", time.Date(2024, 6, 15, 0, 0, 0, 0, timezone), time.Date(2024, 6, 15, 23, 59, 59, 0, timezone)},
+			{"RoundWeek", "now/w", "now/w", time.Date(2024, 6, 10, 0, 0, 0, 0, timezone), time.Date(2024, 6, 16, 23, 59, 59, 0, timezone)},
+			{"LastMonth", "now-1M/M", "now-1M/M", time.Date(2024, 5, 1, 0, 0, 0, 0, timezone), time.Date(2024, 5, 31, 23, 59, 59, 0, timezone)},
+			{"Macro", "$__from-1d", "${__to}+1h", time.Date(2024, 2, 29, 9, 0, 0, 0, timezone), time.Date(2024, 4, 1, 9, 59, 59, 0, timezone)},
+			{"MixedWithFixed", "2024-06-01 00:00:00", "now-2h/h", time.Date(2024, 6, 1, 0, 0, 0, 0, timezone), time.Date(2024, 6, 15, 17, 59, 59, 0, timezone)},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				if respA := queryWithTime(c.startTime, c.endTime); respA.Error != nil {
+					t.Fatalf("failed query of RefID '%s': %s", "A", respA.Error.Error())
+				}
+				cli := ds.Client.(*MockClient)
+				if cli.actualArguments.fromTime == nil {
+					t.Errorf("expected fromTime is %s but nil", c.expectedFromTime.Format("2006-01-02 15:04:05 -07:00"))
+				} else if !cli.actualArguments.fromTime.Equal(c.expectedFromTime) {
+					t.Errorf("expected fromTime is %s but %s", c.expectedFromTime.Format("2006-01-02 15:04:05 -07:00"), cli.actualArguments.fromTime.Format("2006-01-02 15:04:05 -07:00"))
+				}
+				if cli.actualArguments.toTime == nil {
+					t.Errorf("expected toTime is %s but nil", c.expectedToTime.Format("2006-01-02 15:04:05 -07:00"))
+				} else if !cli.actualArguments.toTime.Equal(c.expectedToTime) {
+					t.Errorf("expected toTime is %s but %s", c.expectedToTime.Format("2006-01-02 15:04:05 -07:00"), cli.actualArguments.toTime.Format("2006-01-02 15:04:05 -07:00"))
+				}
+			})
+		}
+	})
+	t.Run("Error", func(t *testing.T) {
+		for _, startTime := range []string{"now-7", "now/x", "now+d", "now*2", "now-1q"} {
+			t.Run(startTime, func(t *testing.T) {
+				if respA := queryWithTime(startTime, "now"); respA.Error == nil {
+					t.Errorf("expected error is %s but nil", "start time parse")
+				} else if !strings.Contains(respA.Error.Error(), "start time parse") {
+					t.Errorf("expected error is %s but %s", "start time parse", respA.Error.Error())
+				}
+			})
+		}
+	})
+}
+
+func TestQueryDataWithTimeShiftAndTimezone(t *testing.T) {
+	ds := Datasource{Settings: model.FiapDatasourceSettings{
+		Url:            "http://test.url:12345",
+		ServerTimezone: "",
+	}}
+	if cli, err := createDefaultMockClient(&ds.Settings); err != nil {
+		t.Fatal(err)
+	} else {
+		ds.Client = cli
+	}
+	query := func(json string) backend.DataResponse {
+		resp, err := ds.QueryData(
+			context.Background(),
+			&backend.QueryDataRequest{
+				Queries: []backend.DataQuery{
+					{
+						RefID: "A",
+						JSON:  []byte(json),
+						TimeRange: backend.TimeRange{
+							From: time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC),
+							To:   time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC),
+						},
+					},
+				},
+			},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Responses["A"]
+	}
+	t.Run("Normal", func(t *testing.T) {
+		t.Run("TimeShift", func(t *testing.T) {
+			respA := query(`{"point_ids":[{"point_id":"id_a"},{"point_id":"id_b","start_time":{"time":"2024-03-20 00:00:00","link_dashboard":false}}],"data_range":"period","start_time":{"time":"","link_dashboard":true},"end_time":{"time":"","link_dashboard":true},"time_shift":"1w"}`)
+			if respA.Error != nil {
+				t.Fatalf("failed query of RefID '%s': %s", "A", respA.Error.Error())
+			}
+			cli := ds.Client.(*MockClient)
+			if expected := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC); cli.actualArguments.fromTime == nil || !cli.actualArguments.fromTime.Equal(expected) {
+				t.Errorf("expected fromTime is %s but %v", expected.Format("2006-01-02 15:04:05 -07:00"), cli.actualArguments.fromTime)
+			}
+			if expected := time.Date(2024, 3, 24, 23, 59, 59, 0, time.UTC); cli.actualArguments.toTime == nil || !cli.actualArguments.toTime.Equal(expected) {
+				t.Errorf("expected toTime is %s but %v", expected.Format("2006-01-02 15:04:05 -07:00"), cli.actualArguments.toTime)
+			}
+			if expected, actual := time.Date(2024, 3, 13, 0, 0, 0, 0, time.UTC), cli.actualArguments.pointIDs[1].FromTime; actual == nil || !actual.Equal(expected) {
+				t.Errorf("expected fromTime of pointID[1] is %s but %v", expected.Format("2006-01-02 15:04:05 -07:00"), actual)
+			}
+			// the mock client returns the time range as the timestamps.
+			for _, frame := range respA.Frames {
+				if actual := frame.Fields[0].At(0).(time.Time); !actual.Equal(time.Date(2024, 3, 8, 0, 0, 0, 0, time.UTC)) {
+					t.Errorf("expected first time of frame '%s' is %s but %s", frame.Name, "2024-03-08 00:00:00 +00:00", actual.Format("2006-01-02 15:04:05 -07:00"))
+				}
+				if actual := frame.Fields[0].At(1).(time.Time); !actual.Equal(time.Date(2024, 3, 31, 23, 59, 59, 0, time.UTC)) {
+					t.Errorf("expected last time of frame '%s' is %s but %s", frame.Name, "2024-03-31 23:59:59 +00:00", actual.Format("2006-01-02 15:04:05 -07:00"))
+				}
+			}
+		})
+		t.Run("TimeShiftMonthEnd", func(t *testing.T) {
+			respA := query(`{"point_ids":[{"point_id":"id_a"}],"data_range":"period","start_time":{"time":"","link_dashboard":true},"end_time":{"time":"","link_dashboard":true},"time_shift":"1M"}`)
+			if respA.Error != nil {
+				t.Fatalf("failed query of RefID '%s': %s", "A", respA.Error.Error())
+			}
+			cli := ds.Client.(*MockClient)
+			if expected := time.Date(2024, 2, 8, 0, 0, 0, 0, time.UTC); cli.actualArguments.fromTime == nil || !cli.actualArguments.fromTime.Equal(expected) {
+				t.Errorf("expected fromTime is %s but %v", expected.Format("2006-01-02 15:04:05 -07:00"), cli.actualArguments.fromTime)
+			}
+			// the end of March is clamped to the end of February instead of overflowing into March.
+			if expected := time.Date(2024, 2, 29, 23, 59, 59, 0, time.UTC); cli.actualArguments.toTime == nil || !cli.actualArguments.toTime.Equal(expected) {
+				t.Errorf("expected toTime is %s but %v", expected.Format("2006-01-02 15:04:05 -07:00"), cli.actualArguments.toTime)
+			}
+			// the timestamps are shifted forward by one month from the end of February.
+			if actual := respA.Frames[0].Fields[0].At(1).(time.Time); !actual.Equal(time.Date(2024, 3, 29, 23, 59, 59, 0, time.UTC)) {
+				t.Errorf("expected last time is %s but %s", "2024-03-29 23:59:59 +00:00", actual.Format("2006-01-02 15:04:05 -07:00"))
+			}
+		})
+		t.Run("Timezone", func(t *testing.T) {
+			respA := query(`{"point_ids":[{"point_id":"id_a"}],"data_range":"period","start_time":{"time":"2024-06-01 00:00:00","link_dashboard":false},"end_time":{"time":"2024-06-30 23:59:59","link_dashboard":false},"timezone":"Asia/Tokyo"}`)
+			if respA.Error != nil {
+				t.Fatalf("failed query of RefID '%s': %s", "A", respA.Error.Error())
+			}
+			cli := ds.Client.(*MockClient)
+			if expected := time.Date(2024, 5, 31, 15, 0, 0, 0, time.UTC); cli.actualArguments.fromTime == nil || !cli.actualArguments.fromTime.Equal(expected) {
+				t.Errorf("expected fromTime is %s but %v", expected.Format("2006-01-02 15:04:05 -07:00"), cli.actualArguments.fromTime)
+			}
+			if expected := time.Date(2024, 6, 30, 14, 59, 59, 0, time.UTC); cli.actualArguments.toTime == nil || !cli.actualArguments.toTime.Equal(expected) {
+				t.Errorf("expected toTime is %s but %v", expected.Format("2006-01-02 15:04:05 -07:00"), cli.actualArguments.toTime)
+			}
+		})
+	})
+	t.Run("Error", func(t *testing.T) {
+		t.Run("InvalidTimeShift", func(t *testing.T) {
+			respA := query(`{"point_ids":[{"point_id":"id_a"}],"data_range":"period","start_time":{"time":"","link_dashboard":true},"end_time":{"time":"","link_dashboard":true},"time_shift":"1"}`)
+			if respA.Error == nil {
+				t.Errorf("expected error is %s but nil", "time shift parse")
+			} else if !strings.Contains(respA.Error.Error(), "time shift parse") {
+				t.Errorf("expected error is %s but %s", "time shift parse", respA.Error.Error())
+			}
+		})
+		t.Run("InvalidTimezone", func(t *testing.T) {
+			respA := query(`{"point_ids":[{"point_id":"id_a"}],"data_range":"period","start_time":{"time":"","link_dashboard":true},"end_time":{"time":"","link_dashboard":true},"timezone":"Mars/Base"}`)
+			if respA.Error == nil {
+				t.Errorf("expected error is %s but nil", "timezone parse")
+			} else if !strings.Contains(respA.Error.Error(), "timezone parse") {
+				t.Errorf("expected error is %s but %s", "timezone parse", respA.Error.Error())
+			}
+		})
+	})
+}
+
+func TestQueryDataWithProcessing(t *testing.T) {
+	ds := Datasource{Settings: model.FiapDatasourceSettings{
+		Url:            "http://test.url:12345",
+		ServerTimezone: "+09:00",
+	}}
+	if cli, err := createDefaultMockClient(&ds.Settings); err != nil {
+		t.Fatal(err)
+	} else {
+		ds.Client = cli
+	}
+	query := func(json string) backend.DataResponse {
+		resp, err := ds.QueryData(
+			context.Background(),
+			&backend.QueryDataRequest{
+				Queries: []backend.DataQuery{
+					{
+						RefID:    "A",
+						JSON:     []byte(json),
+						Interval: 24 * time.Hour,
+						TimeRange: backend.TimeRange{
+							From: time.Date(2024, 5, 1, 14, 0, 0, 0, time.UTC),
+							To:   time.Date(2024, 5, 1, 16, 0, 0, 0, time.UTC),
+						},
+					},
+				},
+			},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Responses["A"]
+	}
+	t.Run("Normal", func(t *testing.T) {
+		t.Run("DefaultInterval", func(t *testing.T) {
+			respA := query(`{"point_ids":[{"point_id":"id_a"}],"data_range":"period","start_time":{"time":"","link_dashboard":true},"end_time":{"time":"","link_dashboard":true},"aggregation":"sum"}`)
+			if respA.Error != nil {
+				t.Fatalf("failed query of RefID '%s': %s", "A", respA.Error.Error())
+			}
+			// the mock client returns 10 at 23:00 and 20 at 01:00 of the next day in the server timezone.
+			frame := respA.Frames[0]
+			if frame.Rows() != 2 {
+				t.Fatalf("expected rows are %d but %d", 2, frame.Rows())
+			}
+			if expected, actual := time.Date(2024, 5, 1, 15, 0, 0, 0, time.UTC), frame.Fields[0].At(1).(time.Time); !actual.Equal(expected) {
+				t.Errorf("expected time is %s but %s", expected, actual)
+			}
+			if actual := frame.Fields[1].At(0).(*float64); actual == nil || *actual != 10 {
+				t.Errorf("expected value is %v but %v", 10, actual)
+			}
+		})
+		t.Run("IncreaseAndAggregation", func(t *testing.T) {
+			respA := query(`{"point_ids":[{"point_id":"id_a"}],"data_range":"period","start_time":{"time":"","link_dashboard":true},"end_time":{"time":"","link_dashboard":true},"transform":"increase","aggregation":"sum","aggregation_interval":"1M"}`)
+			if respA.Error != nil {
+				t.Fatalf("failed query of RefID '%s': %s", "A", respA.Error.Error())
+			}
+			// the increase from 10 to 20 is counted in the bucket of the month containing the later value.
+			frame := respA.Frames[0]
+			if frame.Rows() != 1 {
+				t.Fatalf("expected rows are %d but %d", 1, frame.Rows())
+			}
+			if actual := frame.Fields[1].At(0).(*float64); actual == nil || *actual != 10 {
+				t.Errorf("expected value is %v but %v", 10, actual)
+			}
+		})
+		t.Run("AggregationWithEmptyPoint", func(t *testing.T) {
+			respA := query(`{"point_ids":[{"point_id":"id_a"},{"point_id":"id_empty"}],"data_range":"period","start_time":{"time":"","link_dashboard":true},"end_time":{"time":"","link_dashboard":true},"aggregation":"avg"}`)
+			if respA.Error != nil {
+				t.Fatalf("failed query of RefID '%s': %s", "A", respA.Error.Error())
+			}
+			if len(respA.Frames) != 2 {
+				t.Fatalf("expected frames are %d but %d", 2, len(respA.Frames))
+			}
+			if frame := respA.Frames[1]; frame.Rows() != 0 {
+				t.Errorf("expected the frame of the empty point has no rows but %d", frame.Rows())
+			}
+		})
+		t.Run("WideFrameLayout", func(t *testing.T) {
+			respA := query(`{"point_ids":[{"point_id":"id_a"},{"point_id":"id_b"}],"data_range":"period","start_time":{"time":"","link_dashboard":true},"end_time":{"time":"","link_dashboard":true},"frame_layout":"wide"}`)
+			if respA.Error != nil {
+				t.Fatalf("failed query of RefID '%s': %s", "A", respA.Error.Error())
+			}
+			if len(respA.Frames) != 1 {
+				t.Fatalf("expected frames are %d but %d", 1, len(respA.Frames))
+			}
+			if frame := respA.Frames[0]; frame.Name != "A" || len(frame.Fields) != 3 || frame.Rows() != 2 {
+				t.Errorf("expected a frame of 3 fields and 2 rows but %d fields and %d rows", len(frame.Fields), frame.Rows())
+			}
+		})
+		t.Run("Interval", func(t *testing.T) {
+			respA := query(`{"point_ids":[{"point_id":"id_a"}],"data_range":"period","start_time":{"time":"","link_dashboard":true},"end_time":{"time":"","link_dashboard":true},"aggregation":"last","aggregation_interval":"1M"}`)
+			if respA.Error != nil {
+				t.Fatalf("failed query of RefID '%s': %s", "A", respA.Error.Error())
+			}
+			frame := respA.Frames[0]
+			if frame.Rows() != 1 {
+				t.Fatalf("expected rows are %d but %d", 1, frame.Rows())
+			}
+			if expected, actual := time.Date(2024, 4, 30, 15, 0, 0, 0, time.UTC), frame.Fields[0].At(0).(time.Time); !actual.Equal(expected) {
+				t.Errorf("expected time is %s but %s", expected, actual)
+			}
+			if actual := frame.Fields[1].At(0).(int64); actual != 20 {
+				t.Errorf("expected value is %v but %v", 20, actual)
+			}
+		})
+	})
+	t.Run("Error", func(t *testing.T) {
+		t.Run("InvalidAggregation", func(t *testing.T) {
+			respA := query(`{"point_ids":[{"point_id":"id_a"}],"data_range":"period","start_time":{"time":"","link_dashboard":true},"end_time":{"time":"","link_dashboard":true},"aggregation":"median"}`)
+			if respA.Error == nil {
+				t.Errorf("expected error is %s but nil", "aggregation parse")
+			} else if !strings.Contains(respA.Error.Error(), "aggregation parse") {
+				t.Errorf("expected error is %s but %s", "aggregation parse", respA.Error.Error())
+			}
+		})
+		t.Run("InvalidRateUnit", func(t *testing.T) {
+			respA := query(`{"point_ids":[{"point_id":"id_a"}],"data_range":"period","start_time":{"time":"","link_dashboard":true},"end_time":{"time":"","link_dashboard":true},"transform":"rate","rate_unit":"d"}`)
+			if respA.Error == nil {
+				t.Errorf("expected error is %s but nil", "rate unit parse")
+			} else if !strings.Contains(respA.Error.Error(), "rate unit parse") {
+				t.Errorf("expected error is %s but %s", "rate unit parse", respA.Error.Error())
+			}
+		})
+		t.Run("InvalidFillThreshold", func(t *testing.T) {
+			respA := query(`{"point_ids":[{"point_id":"id_a"}],"data_range":"period","start_time":{"time":"","link_dashboard":true},"end_time":{"time":"","link_dashboard":true},"fill":"null","fill_threshold":"10"}`)
+			if respA.Error == nil {
+				t.Errorf("expected error is %s but nil", "fill threshold parse")
+			} else if !strings.Contains(respA.Error.Error(), "fill threshold parse") {
+				t.Errorf("expected error is %s but %s", "fill threshold parse", respA.Error.Error())
+			}
+		})
+		t.Run("ZeroFillThreshold", func(t *testing.T) {
+			respA := query(`{"point_ids":[{"point_id":"id_a"}],"data_range":"period","start_time":{"time":"","link_dashboard":true},"end_time":{"time":"","link_dashboard":true},"fill":"null","fill_threshold":"0s"}`)
+			if respA.Error == nil {
+				t.Errorf("expected error is %s but nil", "amount must be positive")
+			} else if !strings.Contains(respA.Error.Error(), "amount must be positive") {
+				t.Errorf("expected error is %s but %s", "amount must be positive", respA.Error.Error())
+			}
+		})
+		t.Run("InvalidInterval", func(t *testing.T) {
+			respA := query(`{"point_ids":[{"point_id":"id_a"}],"data_range":"period","start_time":{"time":"","link_dashboard":true},"end_time":{"time":"","link_dashboard":true},"aggregation":"avg","aggregation_interval":"1q"}`)
+			if respA.Error == nil {
+				t.Errorf("expected error is %s but nil", "aggregation interval parse")
+			} else if !strings.Contains(respA.Error.Error(), "aggregation interval parse") {
+				t.Errorf("expected error is %s but %s", "aggregation interval parse", respA.Error.Error())
+			}
+		})
+	})
+}
+
 func TestCheckHealth(t *testing.T) {
 	t.Run("StatusOk", func(t *testing.T) {
 		ds := Datasource{Client: &MockClient{
@@ -730,7 +1375,7 @@ func TestCheckHealth(t *testing.T) {
 			},
 		}}
 
-		res, err := ds.Client.CheckHealth()
+		res, err := ds.Client.CheckHealth(context.Background())
 		if err != nil {
 			t.Error(err)
 		}
@@ -751,7 +1396,7 @@ func TestCheckHealth(t *testing.T) {
 			},
 		}}
 
-		res, err := ds.Client.CheckHealth()
+		res, err := ds.Client.CheckHealth(context.Background())
 		if err != nil {
 			t.Error(err)
 		}
@@ -769,7 +1414,7 @@ func TestCheckHealth(t *testing.T) {
 			},
 		}}
 
-		_, err := ds.Client.CheckHealth()
+		_, err := ds.Client.CheckHealth(context.Background())
 		if err == nil {
 			t.Error("CheckHealth must return an error")
 		} else if !strings.Contains(err.Error(), "test unexpected error") {
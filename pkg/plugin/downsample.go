@@ -0,0 +1,213 @@
+package plugin
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// downsampleFrames reduces the rows of the frames having more rows than maxDataPoints.
+// Only frames of a time field and a float field are downsampled, and a notice is added to them.
+// Null values are not scored, but the first null of each gap is kept so that the gap is still shown.
+func downsampleFrames(frames data.Frames, downsampling dsmodel.DownsamplingType, maxDataPoints int64) {
+	if maxDataPoints <= 0 {
+		return
+	}
+	for _, frame := range frames {
+		rows := frame.Rows()
+		if int64(rows) <= maxDataPoints || !isFloatSeries(frame) {
+			continue
+		}
+		times, values, valueRows := floatSeriesOf(frame)
+		if len(values) == 0 {
+			continue
+		}
+
+		var scored []int
+		switch downsampling {
+		case dsmodel.DownsamplingMinMax:
+			scored = minMaxRows(times, values, int(maxDataPoints))
+		default:
+			scored = lttbRows(times, values, int(maxDataPoints))
+		}
+		selected := withGapRows(frame.Fields[1], valueRows, scored)
+		if len(selected) >= rows {
+			continue
+		}
+		selectRows(frame, selected)
+		frame.AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityInfo,
+			Text:     fmt.Sprintf("The series is downsampled from %d to %d points by %s.", rows, len(selected), downsampling),
+		})
+	}
+}
+
+// isFloatSeries returns whether the frame consists of a time field and a float field,
+// which is built by pointsToFloatColumns, pointsToNullableFloatColumns or aggregateFrames.
+func isFloatSeries(frame *data.Frame) bool {
+	if len(frame.Fields) != 2 || frame.Fields[0].Type() != data.FieldTypeTime {
+		return false
+	}
+	return frame.Fields[1].Type() == data.FieldTypeFloat64 || frame.Fields[1].Type() == data.FieldTypeNullableFloat64
+}
+
+// floatSeriesOf returns the times and the values of the frame which are not null with their rows,
+// sorting the rows of the frame by time.
+func floatSeriesOf(frame *data.Frame) ([]time.Time, []float64, []int) {
+	rows := make([]int, frame.Rows())
+	for i := range rows {
+		rows[i] = i
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return frame.Fields[0].At(rows[i]).(time.Time).Before(frame.Fields[0].At(rows[j]).(time.Time))
+	})
+	selectRows(frame, rows)
+	timeField, valueField := frame.Fields[0], frame.Fields[1]
+
+	times := make([]time.Time, 0, len(rows))
+	values := make([]float64, 0, len(rows))
+	valueRows := make([]int, 0, len(rows))
+	for i := range rows {
+		value, err := valueField.NullableFloatAt(i)
+		if err != nil || value == nil {
+			continue
+		}
+		times, values, valueRows = append(times, timeField.At(i).(time.Time)), append(values, *value), append(valueRows, i)
+	}
+	return times, values, valueRows
+}
+
+// withGapRows returns the rows of the frame selected from the values, adding the first row of each run of nulls.
+// valueRows are the rows of the values, and selected are the indexes of the values.
+func withGapRows(valueField *data.Field, valueRows []int, selected []int) []int {
+	rows := make([]int, 0, len(selected))
+	for _, i := range selected {
+		rows = append(rows, valueRows[i])
+	}
+	for i := 0; i < valueField.Len(); i++ {
+		if valueField.NilAt(i) && (i == 0 || !valueField.NilAt(i-1)) {
+			rows = append(rows, i)
+		}
+	}
+	sort.Ints(rows)
+	return rows
+}
+
+// selectRows replaces the fields of the frame with the rows in the order of the given indexes.
+func selectRows(frame *data.Frame, rows []int) {
+	for i, field := range frame.Fields {
+		selected := data.NewFieldFromFieldType(field.Type(), len(rows))
+		for j, row := range rows {
+			selected.Set(j, field.CopyAt(row))
+		}
+		selected.Name, selected.Labels, selected.Config = field.Name, field.Labels, field.Config
+		frame.Fields[i] = selected
+	}
+}
+
+// lttbRows selects the rows by Largest-Triangle-Three-Buckets, which keeps the first and the last rows
+// and the row making the largest triangle with its neighbors in each bucket.
+// The threshold is at least 3 so that a bucket is left between the first and the last rows.
+func lttbRows(times []time.Time, values []float64, threshold int) []int {
+	n := len(values)
+	threshold = max(threshold, 3)
+	if threshold >= n {
+		rows := make([]int, n)
+		for i := range rows {
+			rows[i] = i
+		}
+		return rows
+	}
+	// x is seconds from the first row, which keeps the precision of float64.
+	x := func(i int) float64 {
+		return times[i].Sub(times[0]).Seconds()
+	}
+
+	rows := make([]int, 0, threshold)
+	rows = append(rows, 0)
+	bucketSize := float64(n-2) / float64(threshold-2)
+	previous := 0
+	for bucket := 0; bucket < threshold-2; bucket++ {
+		start := int(float64(bucket)*bucketSize) + 1
+		end := int(float64(bucket+1)*bucketSize) + 1
+
+		// the average of the next bucket is the third point of the triangles.
+		nextStart, nextEnd := end, int(float64(bucket+2)*bucketSize)+1
+		if nextEnd > n {
+			nextEnd = n
+		}
+		if bucket == threshold-3 {
+			nextStart, nextEnd = n-1, n
+		}
+		var avgX, avgY float64
+		for i := nextStart; i < nextEnd; i++ {
+			avgX += x(i)
+			avgY += values[i]
+		}
+		avgX /= float64(nextEnd - nextStart)
+		avgY /= float64(nextEnd - nextStart)
+
+		maxArea, maxRow := -1.0, start
+		for i := start; i < end; i++ {
+			area := math.Abs((x(previous)-avgX)*(values[i]-values[previous]) - (x(previous)-x(i))*(avgY-values[previous]))
+			if area > maxArea {
+				maxArea, maxRow = area, i
+			}
+		}
+		rows = append(rows, maxRow)
+		previous = maxRow
+	}
+	return append(rows, n-1)
+}
+
+// minMaxRows selects the rows of the minimum and the maximum value in each bucket of time,
+// splitting the time range into threshold/2 buckets as pixels of the panel.
+func minMaxRows(times []time.Time, values []float64, threshold int) []int {
+	n := len(values)
+	buckets := threshold / 2
+	if buckets < 1 {
+		buckets = 1
+	}
+	span := times[n-1].Sub(times[0])
+	bucketOf := func(i int) int {
+		if span <= 0 {
+			return 0
+		}
+		bucket := int(float64(times[i].Sub(times[0])) / float64(span) * float64(buckets))
+		if bucket >= buckets {
+			bucket = buckets - 1
+		}
+		return bucket
+	}
+	rows := make([]int, 0, threshold)
+	for i := 0; i < n; {
+		bucket := bucketOf(i)
+		minRow, maxRow := i, i
+		j := i
+		for ; j < n; j++ {
+			if bucketOf(j) != bucket {
+				break
+			}
+			if values[j] < values[minRow] {
+				minRow = j
+			}
+			if values[j] > values[maxRow] {
+				maxRow = j
+			}
+		}
+		if minRow > maxRow {
+			minRow, maxRow = maxRow, minRow
+		}
+		rows = append(rows, minRow)
+		if maxRow != minRow {
+			rows = append(rows, maxRow)
+		}
+		i = j
+	}
+	return rows
+}
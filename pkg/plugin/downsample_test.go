@@ -0,0 +1,184 @@
+package plugin
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestDownsampleFrames(t *testing.T) {
+	baseTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	// a sine wave of 1000 values with a spike at the row 500.
+	newFrame := func() *data.Frame {
+		times := make([]time.Time, 1000)
+		values := make([]float64, 1000)
+		for i := range times {
+			times[i] = baseTime.Add(time.Duration(i) * time.Minute)
+			values[i] = math.Sin(float64(i) / 50)
+		}
+		values[500] = 10
+		return data.NewFrame("A:id_a",
+			data.NewField("time", nil, times),
+			data.NewField("id_a", nil, values).SetConfig(&data.FieldConfig{DisplayNameFromDS: "alias"}),
+		)
+	}
+	containsSpike := func(frame *data.Frame) bool {
+		for i := 0; i < frame.Rows(); i++ {
+			if frame.Fields[1].At(i).(float64) == 10 {
+				return true
+			}
+		}
+		return false
+	}
+	t.Run("Normal", func(t *testing.T) {
+		for _, downsampling := range []dsmodel.DownsamplingType{dsmodel.DownsamplingLTTB, dsmodel.DownsamplingMinMax} {
+			t.Run(string(downsampling), func(t *testing.T) {
+				frame := newFrame()
+				downsampleFrames(data.Frames{frame}, downsampling, 100)
+				if frame.Rows() > 100 || frame.Rows() < 50 {
+					t.Errorf("expected rows are at most %d but %d", 100, frame.Rows())
+				}
+				if !containsSpike(frame) {
+					t.Errorf("expected the spike is kept but not")
+				}
+				if actual := frame.Fields[0].At(0).(time.Time); !actual.Equal(baseTime) {
+					t.Errorf("expected first time is %s but %s", baseTime, actual)
+				}
+				for i := 1; i < frame.Rows(); i++ {
+					if !frame.Fields[0].At(i - 1).(time.Time).Before(frame.Fields[0].At(i).(time.Time)) {
+						t.Errorf("expected times are sorted but time[%d] is not", i)
+					}
+				}
+				if frame.Fields[1].Config == nil || frame.Fields[1].Config.DisplayNameFromDS != "alias" {
+					t.Errorf("expected config is kept but %v", frame.Fields[1].Config)
+				}
+				if len(frame.Meta.Notices) != 1 || !strings.Contains(frame.Meta.Notices[0].Text, "downsampled from 1000") {
+					t.Errorf("expected a notice of downsampling but %v", frame.Meta.Notices)
+				}
+			})
+		}
+		t.Run("LTTBUnsorted", func(t *testing.T) {
+			frame := data.NewFrame("A:id_a",
+				data.NewField("time", nil, []time.Time{baseTime.Add(3 * time.Minute), baseTime, baseTime.Add(2 * time.Minute), baseTime.Add(time.Minute)}),
+				data.NewField("id_a", nil, []float64{3, 0, 2, 5}),
+			)
+			downsampleFrames(data.Frames{frame}, dsmodel.DownsamplingLTTB, 3)
+			expected := []float64{0, 5, 3}
+			if frame.Rows() != len(expected) {
+				t.Fatalf("expected rows are %d but %d", len(expected), frame.Rows())
+			}
+			for i := range expected {
+				if actual := frame.Fields[1].At(i).(float64); actual != expected[i] {
+					t.Errorf("expected value[%d] is %v but %v", i, expected[i], actual)
+				}
+			}
+		})
+		t.Run("LTTBSmallThreshold", func(t *testing.T) {
+			// the threshold less than 3 is the same as 3: the first, the spike and the last rows.
+			for _, maxDataPoints := range []int64{1, 2} {
+				frame := newFrame()
+				downsampleFrames(data.Frames{frame}, dsmodel.DownsamplingLTTB, maxDataPoints)
+				expected := []time.Time{baseTime, baseTime.Add(500 * time.Minute), baseTime.Add(999 * time.Minute)}
+				if frame.Rows() != len(expected) {
+					t.Fatalf("expected rows of max data points %d are %d but %d", maxDataPoints, len(expected), frame.Rows())
+				}
+				for i := range expected {
+					if actual := frame.Fields[0].At(i).(time.Time); !actual.Equal(expected[i]) {
+						t.Errorf("expected time[%d] of max data points %d is %s but %s", i, maxDataPoints, expected[i], actual)
+					}
+				}
+			}
+		})
+		t.Run("NullableWithGap", func(t *testing.T) {
+			frame := newFrame()
+			values := make([]*float64, frame.Rows())
+			for i := range values {
+				// a gap of 10 minutes.
+				if i < 300 || i >= 310 {
+					values[i] = pointerOf(frame.Fields[1].At(i).(float64))
+				}
+			}
+			frame.Fields[1] = data.NewField("id_a", nil, values)
+			downsampleFrames(data.Frames{frame}, dsmodel.DownsamplingLTTB, 100)
+			if frame.Rows() > 101 {
+				t.Errorf("expected rows are at most %d but %d", 101, frame.Rows())
+			}
+			nulls := 0
+			for i := 0; i < frame.Rows(); i++ {
+				if frame.Fields[1].At(i).(*float64) == nil {
+					nulls++
+					if expected, actual := baseTime.Add(300*time.Minute), frame.Fields[0].At(i).(time.Time); !actual.Equal(expected) {
+						t.Errorf("expected time of the gap is %s but %s", expected, actual)
+					}
+				}
+			}
+			if nulls != 1 {
+				t.Errorf("expected the gap is kept as %d null but %d", 1, nulls)
+			}
+		})
+		t.Run("WithAggregation", func(t *testing.T) {
+			// the averages of 5 minutes are nullable numbers, and the bucket without numbers is null.
+			frame := newFrame()
+			values := make([]*float64, frame.Rows())
+			for i := range values {
+				if i < 600 || i >= 605 {
+					values[i] = pointerOf(frame.Fields[1].At(i).(float64))
+				}
+			}
+			frame.Fields[1] = data.NewField("id_a", nil, values)
+			if err := aggregateFrames(data.Frames{frame}, dsmodel.AggregationAvg, dsmodel.IntervalOf(5*time.Minute), time.UTC); err != nil {
+				t.Fatal(err)
+			}
+			if frame.Rows() != 200 {
+				t.Fatalf("expected rows are %d but %d", 200, frame.Rows())
+			}
+			downsampleFrames(data.Frames{frame}, dsmodel.DownsamplingMinMax, 50)
+			if frame.Rows() > 51 || frame.Rows() < 25 {
+				t.Errorf("expected rows are at most %d but %d", 51, frame.Rows())
+			}
+			nulls := 0
+			for i := 0; i < frame.Rows(); i++ {
+				if frame.Fields[1].At(i).(*float64) == nil {
+					nulls++
+				}
+			}
+			if nulls != 1 {
+				t.Errorf("expected the empty bucket is kept as %d null but %d", 1, nulls)
+			}
+			if len(frame.Meta.Notices) != 1 || !strings.Contains(frame.Meta.Notices[0].Text, "downsampled from 200") {
+				t.Errorf("expected a notice of downsampling but %v", frame.Meta.Notices)
+			}
+		})
+	})
+	t.Run("NotDownsampled", func(t *testing.T) {
+		t.Run("FewRows", func(t *testing.T) {
+			frame := newFrame()
+			downsampleFrames(data.Frames{frame}, dsmodel.DownsamplingLTTB, 1000)
+			if frame.Rows() != 1000 || frame.Meta != nil {
+				t.Errorf("expected frame is not changed but %d rows", frame.Rows())
+			}
+		})
+		t.Run("NoMaxDataPoints", func(t *testing.T) {
+			frame := newFrame()
+			downsampleFrames(data.Frames{frame}, dsmodel.DownsamplingLTTB, 0)
+			if frame.Rows() != 1000 {
+				t.Errorf("expected frame is not changed but %d rows", frame.Rows())
+			}
+		})
+		t.Run("Strings", func(t *testing.T) {
+			frame := data.NewFrame("A:id_a",
+				data.NewField("time", nil, []time.Time{baseTime, baseTime.Add(time.Minute), baseTime.Add(2 * time.Minute)}),
+				data.NewField("id_a", nil, []string{"a", "b", "c"}),
+			)
+			downsampleFrames(data.Frames{frame}, dsmodel.DownsamplingLTTB, 2)
+			if frame.Rows() != 3 {
+				t.Errorf("expected frame is not changed but %d rows", frame.Rows())
+			}
+		})
+	})
+}
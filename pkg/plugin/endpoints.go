@@ -0,0 +1,154 @@
+package plugin
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// endpoint is a FIAP server of the failover.
+type endpoint struct {
+	url     string
+	fetcher *FetchClient
+
+	healthy   bool
+	failedAt  time.Time
+	lastError string
+}
+
+// endpointStatus is the health of an endpoint shown in the details of the health check.
+type endpointStatus struct {
+	URL       string     `json:"url"`
+	Healthy   bool       `json:"healthy"`
+	FailedAt  *time.Time `json:"failed_at,omitempty"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// endpointPool tracks the health of the endpoints in the order of the failover.
+// Requests go to the first healthy endpoint, and failed endpoints are probed periodically
+// so that the requests fail back to them once they recover.
+type endpointPool struct {
+	mu        sync.Mutex
+	endpoints []*endpoint
+
+	// stop stops the periodic probes.
+	stop context.CancelFunc
+}
+
+func newEndpointPool(fetchers []*FetchClient) *endpointPool {
+	pool := &endpointPool{}
+	for _, fetcher := range fetchers {
+		pool.endpoints = append(pool.endpoints, &endpoint{url: fetcher.ConnectionURL, fetcher: fetcher, healthy: true})
+	}
+	return pool
+}
+
+// candidates returns the endpoints in the order to be requested, which are the healthy ones
+// followed by the failed ones as the last resort.
+func (p *endpointPool) candidates() []*endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	healthy, failed := make([]*endpoint, 0, len(p.endpoints)), make([]*endpoint, 0)
+	for _, ep := range p.endpoints {
+		if ep.healthy {
+			healthy = append(healthy, ep)
+		} else {
+			failed = append(failed, ep)
+		}
+	}
+	return append(healthy, failed...)
+}
+
+// markFailed records the failure of the endpoint.
+func (p *endpointPool) markFailed(ep *endpoint, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ep.healthy {
+		backend.Logger.Warn("Endpoint fails over", "url", ep.url, "error", err)
+	}
+	ep.healthy = false
+	ep.failedAt = timeNow()
+	ep.lastError = err.Error()
+}
+
+// markHealthy records the success of the endpoint.
+func (p *endpointPool) markHealthy(ep *endpoint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !ep.healthy {
+		backend.Logger.Info("Endpoint recovers", "url", ep.url)
+	}
+	ep.healthy = true
+	ep.lastError = ""
+}
+
+// failed returns the endpoints which are not healthy.
+func (p *endpointPool) failed() []*endpoint {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	failed := make([]*endpoint, 0)
+	for _, ep := range p.endpoints {
+		if !ep.healthy {
+			failed = append(failed, ep)
+		}
+	}
+	return failed
+}
+
+func (p *endpointPool) statuses() []endpointStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	statuses := make([]endpointStatus, len(p.endpoints))
+	for i, ep := range p.endpoints {
+		statuses[i] = endpointStatus{URL: ep.url, Healthy: ep.healthy, LastError: ep.lastError}
+		if !ep.healthy {
+			failedAt := ep.failedAt
+			statuses[i].FailedAt = &failedAt
+		}
+	}
+	return statuses
+}
+
+// probeFailed probes the failed endpoints, and marks the ones passing the probe healthy.
+// Each probe is canceled after the timeout, so that an endpoint which does not respond fails the probe
+// instead of holding the following ones.
+func (p *endpointPool) probeFailed(ctx context.Context, timeout time.Duration, probe func(ctx context.Context, ep *endpoint) error) {
+	for _, ep := range p.failed() {
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := probe(probeCtx, ep)
+		cancel()
+		if err != nil {
+			p.markFailed(ep, err)
+		} else {
+			p.markHealthy(ep)
+		}
+	}
+}
+
+// startProbing probes the failed endpoints at the interval until Close is called.
+// A probe taking longer than the timeout fails.
+func (p *endpointPool) startProbing(interval time.Duration, timeout time.Duration, probe func(ctx context.Context, ep *endpoint) error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.stop = cancel
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.probeFailed(ctx, timeout, probe)
+			}
+		}
+	}()
+}
+
+// Close stops the periodic probes.
+func (p *endpointPool) Close() {
+	if p.stop != nil {
+		p.stop()
+	}
+}
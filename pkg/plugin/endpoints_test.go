@@ -0,0 +1,178 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestFetchWithDateRangeFailover(t *testing.T) {
+	fromTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	toTime := time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC)
+	newClient := func(t *testing.T, settings *dsmodel.FiapDatasourceSettings) *ClientImpl {
+		t.Helper()
+		cli, err := CreateFiapApiClient(settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { cli.(*ClientImpl).Close() })
+		return cli.(*ClientImpl)
+	}
+	fetch := func(t *testing.T, cli *ClientImpl) (*backend.DataResponse, error) {
+		t.Helper()
+		resp := &backend.DataResponse{}
+		err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, []dsmodel.PointID{{Value: "id_a"}}, &backend.DataQuery{RefID: "A"}, nil)
+		return resp, err
+	}
+	endpointOf := func(resp *backend.DataResponse) interface{} {
+		if len(resp.Frames) == 0 || resp.Frames[0].Meta == nil {
+			return nil
+		}
+		return resp.Frames[0].Meta.Custom.(map[string]interface{})["endpoint"]
+	}
+
+	t.Run("FailoverAndFailback", func(t *testing.T) {
+		primary, standby := newFakeFiapServer(t, withStatus(http.StatusServiceUnavailable)), newFakeFiapServer(t)
+		cli := newClient(t, &dsmodel.FiapDatasourceSettings{Url: primary.URL, StandbyUrls: []string{standby.URL}})
+
+		resp, err := fetch(t, cli)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := endpointOf(resp); actual != standby.URL {
+			t.Errorf("expected endpoint is %s but %v", standby.URL, actual)
+		}
+		// the failed primary is skipped until it passes the probe.
+		if _, err := fetch(t, cli); err != nil {
+			t.Fatal(err)
+		}
+		if requests := len(primary.requests()); requests != 1 {
+			t.Errorf("expected 1 request to the failed primary but %d", requests)
+		}
+		if statuses := cli.endpoints.statuses(); statuses[0].Healthy || statuses[0].FailedAt == nil || !statuses[1].Healthy {
+			t.Errorf("expected the primary is failed but %+v", statuses)
+		}
+
+		cli.endpoints.probeFailed(context.Background(), time.Minute, cli.probeEndpoint)
+		if statuses := cli.endpoints.statuses(); statuses[0].Healthy {
+			t.Errorf("expected the primary is still failed but %+v", statuses)
+		}
+		primary.setResponses(fakeResponse{})
+		cli.endpoints.probeFailed(context.Background(), time.Minute, cli.probeEndpoint)
+		resp, err = fetch(t, cli)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := endpointOf(resp); actual != primary.URL {
+			t.Errorf("expected endpoint fails back to %s but %v", primary.URL, actual)
+		}
+	})
+	t.Run("ProbeTimeout", func(t *testing.T) {
+		primary, standby := newFakeFiapServer(t, withStatus(http.StatusServiceUnavailable)), newFakeFiapServer(t)
+		cli := newClient(t, &dsmodel.FiapDatasourceSettings{Url: primary.URL, StandbyUrls: []string{standby.URL}})
+		if _, err := fetch(t, cli); err != nil {
+			t.Fatal(err)
+		}
+		primary.setResponses(fakeResponse{hang: true})
+		start := time.Now()
+		cli.endpoints.probeFailed(context.Background(), 50*time.Millisecond, cli.probeEndpoint)
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("expected the probe is canceled by the timeout but it took %v", elapsed)
+		}
+		if statuses := cli.endpoints.statuses(); statuses[0].Healthy {
+			t.Errorf("expected the primary fails the probe but %+v", statuses)
+		}
+	})
+	t.Run("ByPage", func(t *testing.T) {
+		primary, standby := newFakeFiapServer(t, withStatus(http.StatusBadGateway)), newFakeFiapServer(t)
+		cli := newClient(t, &dsmodel.FiapDatasourceSettings{Url: primary.URL, StandbyUrls: []string{standby.URL}, AcceptableSize: 10})
+		resp, err := fetch(t, cli)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := endpointOf(resp); actual != standby.URL {
+			t.Errorf("expected endpoint is %s but %v", standby.URL, actual)
+		}
+	})
+	t.Run("AllFailed", func(t *testing.T) {
+		primary, standby := newFakeFiapServer(t, withStatus(http.StatusServiceUnavailable)), newFakeFiapServer(t, withStatus(http.StatusServiceUnavailable))
+		cli := newClient(t, &dsmodel.FiapDatasourceSettings{Url: primary.URL, StandbyUrls: []string{standby.URL}})
+		if _, err := fetch(t, cli); err == nil {
+			t.Error("expected error but nil")
+		}
+		// the failed endpoints are still requested as the last resort.
+		if _, err := fetch(t, cli); err == nil {
+			t.Error("expected error but nil")
+		}
+		if len(primary.requests()) != 2 || len(standby.requests()) != 2 {
+			t.Errorf("expected 2 requests to each endpoint but %d, %d", len(primary.requests()), len(standby.requests()))
+		}
+	})
+	t.Run("ClientError", func(t *testing.T) {
+		primary, standby := newFakeFiapServer(t, withStatus(http.StatusBadRequest)), newFakeFiapServer(t)
+		cli := newClient(t, &dsmodel.FiapDatasourceSettings{Url: primary.URL, StandbyUrls: []string{standby.URL}})
+		if _, err := fetch(t, cli); err == nil {
+			t.Error("expected error of the client error but nil")
+		}
+		if requests := len(standby.requests()); requests != 0 {
+			t.Errorf("expected no requests to the standby but %d", requests)
+		}
+	})
+	t.Run("SingleEndpoint", func(t *testing.T) {
+		primary := newFakeFiapServer(t)
+		cli := newClient(t, &dsmodel.FiapDatasourceSettings{Url: primary.URL, StandbyUrls: []string{primary.URL}})
+		if cli.endpoints != nil {
+			t.Error("expected no failover of the duplicated endpoint")
+		}
+		resp, err := fetch(t, cli)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := endpointOf(resp); actual != nil {
+			t.Errorf("expected no endpoint in the metadata but %v", actual)
+		}
+	})
+	t.Run("InvalidFailbackInterval", func(t *testing.T) {
+		if _, err := CreateFiapApiClient(&dsmodel.FiapDatasourceSettings{Url: "http://fiap.example", FailbackInterval: -1}); err == nil {
+			t.Error("expected error of the failback interval but nil")
+		}
+	})
+}
+
+func TestCheckHealthFailover(t *testing.T) {
+	primary, standby := newFakeFiapServer(t, withStatus(http.StatusServiceUnavailable)), newFakeFiapServer(t)
+	cli, err := CreateFiapApiClient(&dsmodel.FiapDatasourceSettings{Url: primary.URL, StandbyUrls: []string{standby.URL}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cli.(*ClientImpl).Close()
+
+	result, err := cli.CheckHealth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != backend.HealthStatusOk || !strings.Contains(result.Message, standby.URL) {
+		t.Errorf("expected status is %v on the standby but %v: %s", backend.HealthStatusOk, result.Status, result.Message)
+	}
+	details := &healthDetails{}
+	if err := json.Unmarshal(result.JSONDetails, details); err != nil {
+		t.Fatal(err)
+	}
+	if len(details.Endpoints) != 2 || details.Endpoints[0].Healthy || !details.Endpoints[1].Healthy {
+		t.Errorf("expected the primary is failed and the standby is healthy but %+v", details.Endpoints)
+	}
+
+	standby.setResponses(fakeResponse{status: http.StatusServiceUnavailable})
+	if result, err := cli.CheckHealth(context.Background()); err != nil {
+		t.Fatal(err)
+	} else if result.Status != backend.HealthStatusError {
+		t.Errorf("expected status is %v but %v", backend.HealthStatusError, result.Status)
+	}
+}
@@ -0,0 +1,283 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	fiapmodel "github.com/SIOS-Technology-Inc/go-fiap-client/pkg/fiap/model"
+
+	"github.com/SIOS-Technology-Inc/go-fiap-client/pkg/fiap"
+	"github.com/SIOS-Technology-Inc/go-fiap-client/pkg/fiap/tools"
+	"github.com/cockroachdb/errors"
+	"github.com/globusdigital/soap"
+	"github.com/google/uuid"
+)
+
+var _ fiap.Fetcher = (*FetchClient)(nil)
+
+// fiapQueryAction is the SOAP action of FIAP queries.
+const fiapQueryAction = "http://soap.fiap.org/query"
+
+var regexpURL = regexp.MustCompile(`^https?://`)
+
+// FetchClient sends FETCH requests like fiap.FetchClient, through the HTTP client and the request headers
+// given by the datasource settings, such as the authentication.
+type FetchClient struct {
+	ConnectionURL string
+	// HTTPClient sends the requests. http.DefaultClient is used when it is nil.
+	HTTPClient *http.Client
+	// RequestHeaderFn modifies the header of each request when it is not nil.
+	RequestHeaderFn func(http.Header)
+
+	// ctx aborts the requests when it is done. context.Background is used when it is nil.
+	ctx context.Context
+	// responseFn receives each response and its body when it is not nil.
+	responseFn func(resp *http.Response, body []byte)
+}
+
+// WithContext returns the copy of the client sending the requests with the context.
+func (f *FetchClient) WithContext(ctx context.Context) fiap.Fetcher {
+	bound := *f
+	bound.ctx = ctx
+	return &bound
+}
+
+func (f *FetchClient) context() context.Context {
+	if f.ctx == nil {
+		return context.Background()
+	}
+	return f.ctx
+}
+
+func (f *FetchClient) Fetch(keys []fiapmodel.UserInputKey, option *fiapmodel.FetchOption) (pointSets map[string](fiapmodel.ProcessedPointSet), points map[string]([]fiapmodel.Value), fiapErr *fiapmodel.Error, err error) {
+	pointSets = make(map[string](fiapmodel.ProcessedPointSet))
+	points = make(map[string]([]fiapmodel.Value))
+	if option == nil {
+		option = &fiapmodel.FetchOption{}
+	}
+
+	// request the next page until the cursor becomes empty.
+	cursor := ""
+	for i := 1; ; i++ {
+		if err := f.context().Err(); err != nil {
+			return nil, nil, nil, errors.Wrapf(err, "FetchOnce error on loop iteration %d", i)
+		}
+		pagePointSets, pagePoints, nextCursor, fiapErr, err := f.FetchOnce(keys, &fiapmodel.FetchOnceOption{AcceptableSize: option.AcceptableSize, Cursor: cursor})
+		if err != nil {
+			return nil, nil, nil, errors.Wrapf(err, "FetchOnce error on loop iteration %d", i)
+		}
+		if fiapErr != nil {
+			return pointSets, points, fiapErr, nil
+		}
+		mergeFetchResults(pointSets, points, pagePointSets, pagePoints)
+		if nextCursor == "" {
+			return pointSets, points, nil, nil
+		}
+		cursor = nextCursor
+	}
+}
+
+func (f *FetchClient) FetchOnce(keys []fiapmodel.UserInputKey, option *fiapmodel.FetchOnceOption) (pointSets map[string](fiapmodel.ProcessedPointSet), points map[string]([]fiapmodel.Value), cursor string, fiapErr *fiapmodel.Error, err error) {
+	if !regexpURL.MatchString(f.ConnectionURL) {
+		return nil, nil, "", nil, errors.Newf("invalid connectionURL: %s", f.ConnectionURL)
+	}
+	if len(keys) == 0 {
+		return nil, nil, "", nil, errors.New("keys is empty")
+	}
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, nil, "", nil, errors.Newf("keys.ID is empty, key: %#v", keys)
+		}
+	}
+
+	httpResponse, queryRS, err := f.query(keys, option)
+	if err != nil {
+		return nil, nil, "", nil, errors.Wrap(err, "client.Call error")
+	}
+	pointSets, points, cursor, fiapErr, err = processQueryRS(httpResponse, queryRS)
+	if err != nil {
+		return nil, nil, "", nil, errors.Wrap(err, "processQueryRS error")
+	}
+	return pointSets, points, cursor, fiapErr, nil
+}
+
+func (f *FetchClient) FetchByIdsWithKey(key fiapmodel.UserInputKeyNoID, ids ...string) (pointSets map[string](fiapmodel.ProcessedPointSet), points map[string]([]fiapmodel.Value), fiapErr *fiapmodel.Error, err error) {
+	if len(ids) == 0 {
+		return nil, nil, nil, errors.New("ids is empty, set at least one id")
+	}
+	keys := make([]fiapmodel.UserInputKey, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, fiapmodel.UserInputKey{
+			ID:              id,
+			Eq:              key.Eq,
+			Neq:             key.Neq,
+			Lt:              key.Lt,
+			Gt:              key.Gt,
+			Lteq:            key.Lteq,
+			Gteq:            key.Gteq,
+			MinMaxIndicator: key.MinMaxIndicator,
+		})
+	}
+	pointSets, points, fiapErr, err = f.Fetch(keys, &fiapmodel.FetchOption{})
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "Fetch error")
+	}
+	return pointSets, points, fiapErr, nil
+}
+
+func (f *FetchClient) FetchLatest(fromDate *time.Time, untilDate *time.Time, ids ...string) (pointSets map[string](fiapmodel.ProcessedPointSet), points map[string]([]fiapmodel.Value), fiapErr *fiapmodel.Error, err error) {
+	return f.FetchByIdsWithKey(fiapmodel.UserInputKeyNoID{MinMaxIndicator: fiapmodel.SelectTypeMaximum, Gteq: fromDate, Lteq: untilDate}, ids...)
+}
+
+func (f *FetchClient) FetchOldest(fromDate *time.Time, untilDate *time.Time, ids ...string) (pointSets map[string](fiapmodel.ProcessedPointSet), points map[string]([]fiapmodel.Value), fiapErr *fiapmodel.Error, err error) {
+	return f.FetchByIdsWithKey(fiapmodel.UserInputKeyNoID{MinMaxIndicator: fiapmodel.SelectTypeMinimum, Gteq: fromDate, Lteq: untilDate}, ids...)
+}
+
+func (f *FetchClient) FetchDateRange(fromDate *time.Time, untilDate *time.Time, ids ...string) (pointSets map[string](fiapmodel.ProcessedPointSet), points map[string]([]fiapmodel.Value), fiapErr *fiapmodel.Error, err error) {
+	return f.FetchByIdsWithKey(fiapmodel.UserInputKeyNoID{MinMaxIndicator: fiapmodel.SelectTypeNone, Gteq: fromDate, Lteq: untilDate}, ids...)
+}
+
+// query sends the query of the keys, and returns the HTTP response, whose body is already closed, and the parsed response.
+func (f *FetchClient) query(keys []fiapmodel.UserInputKey, option *fiapmodel.FetchOnceOption) (*http.Response, *fiapmodel.QueryRS, error) {
+	queryRS := &fiapmodel.QueryRS{}
+	httpResponse, err := f.newSoapClient().Call(f.context(), fiapQueryAction, newQueryRQ(keys, option), queryRS)
+	if err != nil {
+		return nil, nil, err
+	}
+	return httpResponse, queryRS, nil
+}
+
+func (f *FetchClient) newSoapClient() *soap.Client {
+	client := soap.NewClient(f.ConnectionURL, nil)
+	httpClient := f.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	// responses of error status codes are not SOAP messages of FIAP, so that they are returned as errors with the status code.
+	client.HTTPClientDoFn = func(req *http.Request) (*http.Response, error) {
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if f.responseFn != nil {
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if err != nil {
+				return nil, err
+			}
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			f.responseFn(resp, body)
+		}
+		if resp.StatusCode >= http.StatusBadRequest {
+			resp.Body.Close()
+			return nil, &httpStatusError{statusCode: resp.StatusCode}
+		}
+		return resp, nil
+	}
+	client.RequestHeaderFn = f.RequestHeaderFn
+	return client
+}
+
+// httpStatusError is the error of a response with an error status code.
+type httpStatusError struct {
+	statusCode int
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("http status %d %s", e.statusCode, http.StatusText(e.statusCode))
+}
+
+func newQueryRQ(keys []fiapmodel.UserInputKey, option *fiapmodel.FetchOnceOption) *fiapmodel.QueryRQ {
+	if option == nil {
+		option = &fiapmodel.FetchOnceOption{}
+	}
+	return &fiapmodel.QueryRQ{
+		Transport: &fiapmodel.Transport{
+			Header: &fiapmodel.Header{
+				Query: &fiapmodel.Query{
+					Id:             uuid.NewString(),
+					AcceptableSize: option.AcceptableSize,
+					Type:           "storage",
+					Cursor:         option.Cursor,
+					Key:            tools.UserInputKeysToKeys(roundUpEndTimes(keys)),
+				},
+			},
+		},
+	}
+}
+
+// roundUpEndTimes returns the keys whose inclusive end times are rounded up to whole seconds.
+// The times of the keys are formatted in whole seconds, which would drop the values in the fractional second of the end.
+func roundUpEndTimes(keys []fiapmodel.UserInputKey) []fiapmodel.UserInputKey {
+	rounded := make([]fiapmodel.UserInputKey, len(keys))
+	for i, key := range keys {
+		if key.Lteq != nil {
+			if lteq := key.Lteq.Truncate(time.Second); !lteq.Equal(*key.Lteq) {
+				lteq = lteq.Add(time.Second)
+				key.Lteq = &lteq
+			}
+		}
+		rounded[i] = key
+	}
+	return rounded
+}
+
+// processQueryRS returns the point sets and the points of the response keyed by their IDs, and the cursor of the next page.
+func processQueryRS(httpResponse *http.Response, queryRS *fiapmodel.QueryRS) (pointSets map[string](fiapmodel.ProcessedPointSet), points map[string]([]fiapmodel.Value), cursor string, fiapErr *fiapmodel.Error, err error) {
+	if queryRS.Transport == nil {
+		return nil, nil, "", nil, errors.Newf("queryRS.Transport is nil, http status: %d", httpResponse.StatusCode)
+	}
+	if queryRS.Transport.Header == nil {
+		return nil, nil, "", nil, errors.Newf("queryRS.Transport.Header is nil, http status: %d", httpResponse.StatusCode)
+	}
+	if queryRS.Transport.Header.Error != nil {
+		return nil, nil, "", queryRS.Transport.Header.Error, nil
+	}
+	if queryRS.Transport.Body == nil {
+		return nil, nil, "", nil, errors.Newf("queryRS.Transport.Body is nil, http status: %d", httpResponse.StatusCode)
+	}
+
+	pageSets := make(map[string](fiapmodel.ProcessedPointSet))
+	for _, ps := range queryRS.Transport.Body.PointSet {
+		processed := pageSets[ps.Id]
+		processed.PointSetID = append(processed.PointSetID, ps.PointSetId...)
+		processed.PointID = append(processed.PointID, ps.PointId...)
+		pageSets[ps.Id] = processed
+	}
+	pagePoints := make(map[string]([]fiapmodel.Value))
+	for _, p := range queryRS.Transport.Body.Point {
+		values := p.Value
+		if values == nil {
+			values = []fiapmodel.Value{}
+		}
+		if existing, ok := pagePoints[p.Id]; ok {
+			values = append(existing, values...)
+		}
+		pagePoints[p.Id] = values
+	}
+	if queryRS.Transport.Header.Query != nil {
+		cursor = queryRS.Transport.Header.Query.Cursor
+	}
+	return pageSets, pagePoints, cursor, nil, nil
+}
+
+// mergeFetchResults appends the point sets and the points of a page to the results.
+func mergeFetchResults(pointSets map[string](fiapmodel.ProcessedPointSet), points map[string]([]fiapmodel.Value), pagePointSets map[string](fiapmodel.ProcessedPointSet), pagePoints map[string]([]fiapmodel.Value)) {
+	for id, pagePointSet := range pagePointSets {
+		pointSet := pointSets[id]
+		pointSet.PointSetID = append(pointSet.PointSetID, pagePointSet.PointSetID...)
+		pointSet.PointID = append(pointSet.PointID, pagePointSet.PointID...)
+		pointSets[id] = pointSet
+	}
+	for id, values := range pagePoints {
+		if existing, ok := points[id]; ok {
+			values = append(existing, values...)
+		}
+		points[id] = values
+	}
+}
@@ -0,0 +1,342 @@
+package plugin
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+const testQueryRS = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+<soapenv:Body>
+<ns2:queryRS xmlns:ns2="http://soap.fiap.org/">
+<transport xmlns="http://gutp.jp/fiap/2009/11/">
+<header><OK/><query id="0a90f1fa-9a90-4b8e-a2ca-2b2f9a4b3b6e" type="storage"><key id="id_a" attrName="time"/></query></header>
+<body><point id="id_a"><value time="2024-05-01T00:00:00.000+09:00">12.5</value></point></body>
+</transport>
+</ns2:queryRS>
+</soapenv:Body>
+</soapenv:Envelope>`
+
+// fakeResponse is a response of fakeFiapServer.
+type fakeResponse struct {
+	// status is the status code, which is 200 when it is 0.
+	status int
+	// body is the body of the response, which is testQueryRS when it is empty and the status is 200.
+	body string
+	// truncated cuts off the body in the middle before its Content-Length.
+	truncated bool
+	// pause is the time to wait in the middle of the body after the headers are sent.
+	pause time.Duration
+	// hang never responds until the request is aborted or the test ends.
+	hang bool
+}
+
+// fiapErrorResponse returns the response of the FIAP error of the type.
+func fiapErrorResponse(errorType string) fakeResponse {
+	return fakeResponse{body: strings.Replace(testErrorQueryRS, "%s", errorType, 1)}
+}
+
+// fakeRequest is a request recorded by fakeFiapServer.
+type fakeRequest struct {
+	method string
+	host   string
+	header http.Header
+	body   string
+}
+
+// fakeFiapServer is the FIAP server for tests, which responds in the order of the responses repeating the last one,
+// and records the requests. It rejects requests other than POST like many SOAP endpoints.
+type fakeFiapServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	responses []fakeResponse
+	// base is the number of the requests before the responses are set.
+	base     int
+	recorded []fakeRequest
+}
+
+// fakeServerOption configures fakeFiapServer.
+type fakeServerOption func(*fakeFiapServer)
+
+// withResponses makes the server respond in the order of the responses.
+func withResponses(responses ...fakeResponse) fakeServerOption {
+	return func(s *fakeFiapServer) {
+		s.responses = responses
+	}
+}
+
+// withStatus makes the server respond with the status code.
+func withStatus(status int) fakeServerOption {
+	return withResponses(fakeResponse{status: status})
+}
+
+// withBody makes the server respond with the body and the status 200.
+func withBody(body string) fakeServerOption {
+	return withResponses(fakeResponse{body: body})
+}
+
+// withTLS starts the server with TLS of the config, or of the certificate of httptest when the config is nil.
+func withTLS(config *tls.Config) fakeServerOption {
+	return func(s *fakeFiapServer) {
+		s.TLS = config
+		s.StartTLS()
+	}
+}
+
+// newFakeFiapServer returns the server responding testQueryRS unless the options change it.
+// It is closed at the end of the test.
+func newFakeFiapServer(t *testing.T, options ...fakeServerOption) *fakeFiapServer {
+	t.Helper()
+	release := make(chan struct{})
+	s := &fakeFiapServer{responses: []fakeResponse{{}}}
+	s.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		response := s.responses[min(len(s.recorded)-s.base, len(s.responses)-1)]
+		s.recorded = append(s.recorded, fakeRequest{method: r.Method, host: r.Host, header: r.Header.Clone(), body: string(body)})
+		s.mu.Unlock()
+
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if response.hang {
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+			return
+		}
+		status := response.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if response.body == "" && status == http.StatusOK {
+			response.body = testQueryRS
+		}
+		if response.body != "" {
+			w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+		}
+		if response.truncated {
+			w.Header().Set("Content-Length", strconv.Itoa(len(response.body)))
+			response.body = response.body[:len(response.body)/2]
+		}
+		w.WriteHeader(status)
+		if response.pause > 0 {
+			w.Write([]byte(response.body[:len(response.body)/2]))
+			w.(http.Flusher).Flush()
+			time.Sleep(response.pause)
+			response.body = response.body[len(response.body)/2:]
+		}
+		w.Write([]byte(response.body))
+	}))
+	for _, option := range options {
+		option(s)
+	}
+	if s.URL == "" {
+		s.Start()
+	}
+	t.Cleanup(func() {
+		close(release)
+		s.Close()
+	})
+	return s
+}
+
+// setResponses changes the responses to the following requests.
+func (s *fakeFiapServer) setResponses(responses ...fakeResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses, s.base = responses, len(s.recorded)
+}
+
+// requests returns the requests recorded so far.
+func (s *fakeFiapServer) requests() []fakeRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeRequest{}, s.recorded...)
+}
+
+func TestFetchClientAuth(t *testing.T) {
+	fromTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	toTime := time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC)
+	cases := []struct {
+		name     string
+		settings dsmodel.FiapDatasourceSettings
+		expected string
+	}{
+		{"NoAuth", dsmodel.FiapDatasourceSettings{}, ""},
+		{"Basic", dsmodel.FiapDatasourceSettings{AuthType: dsmodel.BasicAuth, BasicAuthUser: "user", Secure: dsmodel.FiapSecureSettings{BasicAuthPassword: "pass"}}, "Basic dXNlcjpwYXNz"},
+		{"Bearer", dsmodel.FiapDatasourceSettings{AuthType: dsmodel.BearerAuth, Secure: dsmodel.FiapSecureSettings{BearerToken: "token"}}, "Bearer token"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server := newFakeFiapServer(t)
+			settings := c.settings
+			settings.Url = server.URL
+			cli, err := CreateFiapApiClient(&settings)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp := &backend.DataResponse{}
+			if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, []dsmodel.PointID{{Value: "id_a"}}, &backend.DataQuery{RefID: "A"}, nil); err != nil {
+				t.Fatal(err)
+			}
+			if len(resp.Frames) != 1 || resp.Frames[0].Rows() != 1 {
+				t.Errorf("expected one frame of one row but %v", resp.Frames)
+			}
+			result, err := cli.CheckHealth(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Status != backend.HealthStatusOk {
+				t.Errorf("expected status is %v but %v: %s", backend.HealthStatusOk, result.Status, result.Message)
+			}
+
+			requests := server.requests()
+			if len(requests) != 2 {
+				t.Fatalf("expected 2 requests but %d", len(requests))
+			}
+			for i, r := range requests {
+				if actual := r.header.Get("Authorization"); actual != c.expected {
+					t.Errorf("expected Authorization of request[%d] is '%s' but '%s'", i, c.expected, actual)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckHealthUnauthorized(t *testing.T) {
+	server := newFakeFiapServer(t, withStatus(http.StatusUnauthorized))
+	cli, err := CreateFiapApiClient(&dsmodel.FiapDatasourceSettings{Url: server.URL, AuthType: dsmodel.BearerAuth, Secure: dsmodel.FiapSecureSettings{BearerToken: "wrong"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := cli.CheckHealth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Status != backend.HealthStatusError {
+		t.Errorf("expected status is %v but %v", backend.HealthStatusError, result.Status)
+	}
+}
+
+func TestFetchClientCancel(t *testing.T) {
+	fromTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	toTime := time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC)
+	server := newFakeFiapServer(t, withResponses(fakeResponse{hang: true}))
+
+	t.Run("Cancel", func(t *testing.T) {
+		cli, err := CreateFiapApiClient(&dsmodel.FiapDatasourceSettings{Url: server.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+		start := time.Now()
+		resp := &backend.DataResponse{}
+		if err := cli.FetchWithDateRange(ctx, resp, dsmodel.Period, &fromTime, &toTime, []dsmodel.PointID{{Value: "id_a"}}, &backend.DataQuery{RefID: "A"}, nil); err == nil {
+			t.Error("expected error of the cancellation but nil")
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("expected the fetch is aborted soon after the cancellation but it took %v", elapsed)
+		}
+	})
+	t.Run("CanceledBeforePaging", func(t *testing.T) {
+		cli, err := CreateFiapApiClient(&dsmodel.FiapDatasourceSettings{Url: server.URL, AcceptableSize: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		resp := &backend.DataResponse{}
+		if err := cli.FetchWithDateRange(ctx, resp, dsmodel.Period, &fromTime, &toTime, []dsmodel.PointID{{Value: "id_a"}}, &backend.DataQuery{RefID: "A"}, nil); err == nil {
+			t.Error("expected error of the cancellation but nil")
+		}
+	})
+	t.Run("ReadTimeout", func(t *testing.T) {
+		cli, err := CreateFiapApiClient(&dsmodel.FiapDatasourceSettings{Url: server.URL, ReadTimeout: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		result, err := cli.CheckHealth(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Status != backend.HealthStatusError {
+			t.Errorf("expected status is %v but %v", backend.HealthStatusError, result.Status)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("expected the health check times out in about 1s but it took %v", elapsed)
+		}
+	})
+	t.Run("SlowBody", func(t *testing.T) {
+		// the body takes longer than the sum of the timeouts, which bound only the connection and the headers.
+		slowServer := newFakeFiapServer(t, withResponses(fakeResponse{pause: 2100 * time.Millisecond}))
+		cli, err := CreateFiapApiClient(&dsmodel.FiapDatasourceSettings{Url: slowServer.URL, ConnectTimeout: 1, ReadTimeout: 1})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := &backend.DataResponse{}
+		if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, []dsmodel.PointID{{Value: "id_a"}}, &backend.DataQuery{RefID: "A"}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Frames) != 1 || resp.Frames[0].Rows() != 1 {
+			t.Errorf("expected a frame of a row but %v", resp.Frames)
+		}
+	})
+	t.Run("InvalidTimeout", func(t *testing.T) {
+		for _, settings := range []dsmodel.FiapDatasourceSettings{{Url: server.URL, ConnectTimeout: -1}, {Url: server.URL, ReadTimeout: -1}} {
+			if _, err := CreateFiapApiClient(&settings); err == nil {
+				t.Errorf("expected error of the timeouts %d, %d but nil", settings.ConnectTimeout, settings.ReadTimeout)
+			}
+		}
+	})
+}
+
+func TestFetchClientKeyTimes(t *testing.T) {
+	fromTime := time.Date(2024, 5, 1, 0, 0, 0, 500000000, time.UTC)
+	cases := []struct {
+		name     string
+		toTime   time.Time
+		expected string
+	}{
+		{"WholeSecond", time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC), `lteq="2024-05-31T23:59:59Z"`},
+		{"FractionalSecond", time.Date(2024, 5, 31, 12, 0, 0, 999000000, time.UTC), `lteq="2024-05-31T12:00:01Z"`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			server := newFakeFiapServer(t)
+			cli, err := CreateFiapApiClient(&dsmodel.FiapDatasourceSettings{Url: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp := &backend.DataResponse{}
+			if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &c.toTime, []dsmodel.PointID{{Value: "id_a"}}, &backend.DataQuery{RefID: "A"}, nil); err != nil {
+				t.Fatal(err)
+			}
+			body := server.requests()[0].body
+			if !strings.Contains(body, c.expected) {
+				t.Errorf("expected the key has %s but %s", c.expected, body)
+			}
+			// the start time is formatted in whole seconds as it is.
+			if expected := `gteq="2024-05-01T00:00:00Z"`; !strings.Contains(body, expected) {
+				t.Errorf("expected the key has %s but %s", expected, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package plugin
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// maxFilledRows is the maximum number of rows inserted into a frame by fillFrames.
+// Gaps beyond it get a single null, which is enough to break the line of the graph.
+const maxFilledRows = 10000
+
+// fillFrames inserts rows into the gaps between rows which are farther apart than the threshold.
+// The rows are inserted at the median spacing of the frame, and the threshold is a multiple of the median spacing when it is nil.
+// Numeric fields become nullable numbers, and other fields become nullable, where rejected values are never carried.
+func fillFrames(frames data.Frames, mode dsmodel.FillMode, threshold *dsmodel.Interval) {
+	for _, frame := range frames {
+		fillFrame(frame, mode, threshold)
+	}
+}
+
+func fillFrame(frame *data.Frame, mode dsmodel.FillMode, threshold *dsmodel.Interval) {
+	if frame.Rows() < 2 || len(frame.Fields) < 2 || frame.Fields[0].Type() != data.FieldTypeTime {
+		return
+	}
+	rows := make([]int, frame.Rows())
+	for i := range rows {
+		rows[i] = i
+	}
+	timeField := frame.Fields[0]
+	timeAt := func(row int) time.Time {
+		return timeField.At(row).(time.Time)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return timeAt(rows[i]).Before(timeAt(rows[j]))
+	})
+
+	spacing := medianSpacing(rows, timeAt)
+	if spacing <= 0 {
+		return
+	}
+	isGap := func(from time.Time, to time.Time) bool {
+		if threshold == nil {
+			return to.Sub(from) > spacing*dsmodel.DefaultFillThresholdFactor
+		}
+		return to.After(threshold.Add(from))
+	}
+
+	// filledRow is a row of the frame, or an inserted row between the rows before and after when inserted is true.
+	type filledRow struct {
+		time     time.Time
+		row      int
+		before   int
+		after    int
+		inserted bool
+		null     bool
+	}
+	filled := make([]filledRow, 0, len(rows))
+	insertedCount := 0
+	for i, row := range rows {
+		if i > 0 {
+			before, after := rows[i-1], row
+			from, to := timeAt(before), timeAt(after)
+			if isGap(from, to) {
+				count := int((to.Sub(from) - 1) / spacing)
+				if mode == dsmodel.FillNull || count < 1 || insertedCount+count > maxFilledRows {
+					// a null is put where the next row is expected, or in the middle of a gap narrower than the spacing.
+					step := spacing
+					if to.Sub(from) <= spacing {
+						step = to.Sub(from) / 2
+					}
+					filled = append(filled, filledRow{time: from.Add(step), before: before, after: after, inserted: true, null: true})
+					insertedCount++
+				} else {
+					for k := 1; k <= count; k++ {
+						filled = append(filled, filledRow{time: from.Add(time.Duration(k) * spacing), before: before, after: after, inserted: true})
+					}
+					insertedCount += count
+				}
+			}
+		}
+		filled = append(filled, filledRow{time: timeAt(row), row: row})
+	}
+	if insertedCount == 0 {
+		return
+	}
+
+	times := make([]time.Time, len(filled))
+	for i, r := range filled {
+		times[i] = r.time
+	}
+	fields := []*data.Field{data.NewField(timeField.Name, timeField.Labels, times)}
+	fields[0].Config = timeField.Config
+	for _, field := range frame.Fields[1:] {
+		var result *data.Field
+		if field.Type().Numeric() {
+			values := make([]*float64, len(filled))
+			for i, r := range filled {
+				switch {
+				case !r.inserted:
+					values[i], _ = field.NullableFloatAt(r.row)
+				case r.null:
+				case mode == dsmodel.FillPrevious:
+					values[i], _ = field.NullableFloatAt(r.before)
+				case mode == dsmodel.FillLinear:
+					values[i] = interpolateLinear(field, r.before, r.after, timeAt(r.before), timeAt(r.after), r.time)
+				}
+			}
+			result = data.NewField(field.Name, field.Labels, values)
+		} else {
+			result = data.NewFieldFromFieldType(field.Type().NullableType(), len(filled))
+			carry := mode == dsmodel.FillPrevious && !strings.HasSuffix(field.Name, rejectedFieldSuffix)
+			for i, r := range filled {
+				row := r.row
+				if r.inserted {
+					if r.null || !carry {
+						continue
+					}
+					row = r.before
+				}
+				if value, ok := field.ConcreteAt(row); ok {
+					result.SetConcrete(i, value)
+				}
+			}
+			result.Name, result.Labels = field.Name, field.Labels
+		}
+		result.Config = field.Config
+		fields = append(fields, result)
+	}
+	frame.Fields = fields
+}
+
+// medianSpacing returns the median of the durations between the rows sorted by time.
+func medianSpacing(rows []int, timeAt func(row int) time.Time) time.Duration {
+	spacings := make([]time.Duration, 0, len(rows)-1)
+	for i := 1; i < len(rows); i++ {
+		if spacing := timeAt(rows[i]).Sub(timeAt(rows[i-1])); spacing > 0 {
+			spacings = append(spacings, spacing)
+		}
+	}
+	if len(spacings) == 0 {
+		return 0
+	}
+	sort.Slice(spacings, func(i, j int) bool {
+		return spacings[i] < spacings[j]
+	})
+	return spacings[len(spacings)/2]
+}
+
+// interpolateLinear returns the value at dt on the line between the values of the rows before and after,
+// or nil when either of them is null.
+func interpolateLinear(field *data.Field, before int, after int, beforeTime time.Time, afterTime time.Time, dt time.Time) *float64 {
+	beforeValue, err := field.NullableFloatAt(before)
+	if err != nil || beforeValue == nil {
+		return nil
+	}
+	afterValue, err := field.NullableFloatAt(after)
+	if err != nil || afterValue == nil {
+		return nil
+	}
+	ratio := float64(dt.Sub(beforeTime)) / float64(afterTime.Sub(beforeTime))
+	value := *beforeValue + (*afterValue-*beforeValue)*ratio
+	return &value
+}
@@ -0,0 +1,113 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestFillFrames(t *testing.T) {
+	baseTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	minutes := func(values ...int) []time.Time {
+		times := make([]time.Time, len(values))
+		for i, value := range values {
+			times[i] = baseTime.Add(time.Duration(value) * time.Minute)
+		}
+		return times
+	}
+	// values every minute with a gap from 3 to 7 minutes.
+	newFrame := func() *data.Frame {
+		return data.NewFrame("A:id_a",
+			data.NewField("time", nil, minutes(0, 1, 2, 3, 7, 8)),
+			data.NewField("id_a", nil, []float64{0, 1, 2, 3, 7, 8}).SetConfig(&data.FieldConfig{DisplayNameFromDS: "alias"}),
+			data.NewField("id_a"+rejectedFieldSuffix, nil, []*string{nil, nil, nil, pointerOf("error"), nil, nil}),
+		)
+	}
+	checkFrame := func(t *testing.T, frame *data.Frame, expectedTimes []time.Time, expectedValues []*float64) {
+		t.Helper()
+		if frame.Rows() != len(expectedTimes) {
+			t.Fatalf("expected rows are %d but %d", len(expectedTimes), frame.Rows())
+		}
+		for i := range expectedTimes {
+			if actual := frame.Fields[0].At(i).(time.Time); !actual.Equal(expectedTimes[i]) {
+				t.Errorf("expected time[%d] is %s but %s", i, expectedTimes[i], actual)
+			}
+			actual := frame.Fields[1].At(i).(*float64)
+			if expectedValues[i] == nil && actual != nil {
+				t.Errorf("expected value[%d] is nil but %v", i, *actual)
+			} else if expectedValues[i] != nil && (actual == nil || *actual != *expectedValues[i]) {
+				t.Errorf("expected value[%d] is %v but %v", i, *expectedValues[i], actual)
+			}
+		}
+		if frame.Fields[1].Config == nil || frame.Fields[1].Config.DisplayNameFromDS != "alias" {
+			t.Errorf("expected config is kept but %v", frame.Fields[1].Config)
+		}
+	}
+	t.Run("Normal", func(t *testing.T) {
+		t.Run("Null", func(t *testing.T) {
+			frame := newFrame()
+			fillFrames(data.Frames{frame}, dsmodel.FillNull, nil)
+			checkFrame(t, frame, minutes(0, 1, 2, 3, 4, 7, 8),
+				[]*float64{pointerOf(0.0), pointerOf(1.0), pointerOf(2.0), pointerOf(3.0), nil, pointerOf(7.0), pointerOf(8.0)})
+		})
+		t.Run("Previous", func(t *testing.T) {
+			frame := newFrame()
+			fillFrames(data.Frames{frame}, dsmodel.FillPrevious, nil)
+			checkFrame(t, frame, minutes(0, 1, 2, 3, 4, 5, 6, 7, 8),
+				[]*float64{pointerOf(0.0), pointerOf(1.0), pointerOf(2.0), pointerOf(3.0), pointerOf(3.0), pointerOf(3.0), pointerOf(3.0), pointerOf(7.0), pointerOf(8.0)})
+			// rejected values are not carried.
+			if actual := frame.Fields[2].At(4).(*string); actual != nil {
+				t.Errorf("expected rejected value of filled row is nil but %s", *actual)
+			}
+			if actual := frame.Fields[2].At(3).(*string); actual == nil || *actual != "error" {
+				t.Errorf("expected rejected value is %s but %v", "error", actual)
+			}
+		})
+		t.Run("Linear", func(t *testing.T) {
+			frame := newFrame()
+			fillFrames(data.Frames{frame}, dsmodel.FillLinear, nil)
+			checkFrame(t, frame, minutes(0, 1, 2, 3, 4, 5, 6, 7, 8),
+				[]*float64{pointerOf(0.0), pointerOf(1.0), pointerOf(2.0), pointerOf(3.0), pointerOf(4.0), pointerOf(5.0), pointerOf(6.0), pointerOf(7.0), pointerOf(8.0)})
+		})
+		t.Run("Threshold", func(t *testing.T) {
+			threshold, err := dsmodel.ParseInterval("5m")
+			if err != nil {
+				t.Fatal(err)
+			}
+			frame := newFrame()
+			fillFrames(data.Frames{frame}, dsmodel.FillNull, &threshold)
+			if frame.Rows() != 6 {
+				t.Errorf("expected gap narrower than threshold is not filled but %d rows", frame.Rows())
+			}
+		})
+		t.Run("Strings", func(t *testing.T) {
+			frame := data.NewFrame("A:id_a",
+				data.NewField("time", nil, minutes(0, 1, 2, 6)),
+				data.NewField("id_a", nil, []string{"on", "off", "on", "off"}),
+			)
+			fillFrames(data.Frames{frame}, dsmodel.FillPrevious, nil)
+			expected := []string{"on", "off", "on", "on", "on", "on", "off"}
+			if frame.Rows() != len(expected) {
+				t.Fatalf("expected rows are %d but %d", len(expected), frame.Rows())
+			}
+			for i := range expected {
+				if actual := frame.Fields[1].At(i).(*string); actual == nil || *actual != expected[i] {
+					t.Errorf("expected value[%d] is %s but %v", i, expected[i], actual)
+				}
+			}
+		})
+		t.Run("NoGap", func(t *testing.T) {
+			frame := data.NewFrame("A:id_a",
+				data.NewField("time", nil, minutes(0, 1, 2)),
+				data.NewField("id_a", nil, []float64{0, 1, 2}),
+			)
+			fillFrames(data.Frames{frame}, dsmodel.FillLinear, nil)
+			if frame.Fields[1].Type() != data.FieldTypeFloat64 {
+				t.Errorf("expected frame without gaps is not changed but %s", frame.Fields[1].Type())
+			}
+		})
+	})
+}
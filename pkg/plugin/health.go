@@ -0,0 +1,175 @@
+package plugin
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	fiapmodel "github.com/SIOS-Technology-Inc/go-fiap-client/pkg/fiap/model"
+
+	"github.com/cockroachdb/errors"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// defaultProbePointID is the point ID of the health check query when no probe point is configured.
+// Servers usually answer it with a FIAP error, which still shows that they speak FIAP.
+const defaultProbePointID = "http://fiap.invalid/grafana/health-check"
+
+// maxHealthResponseSize is the maximum bytes of the server response shown in the details of the health check.
+const maxHealthResponseSize = 4096
+
+// healthDetails are the diagnostics of the health check returned in CheckHealthResult.JSONDetails.
+type healthDetails struct {
+	URL           string      `json:"url"`
+	ProbePointID  string      `json:"probe_point_id"`
+	LatencyMs     int64       `json:"latency_ms"`
+	StatusCode    int         `json:"status_code,omitempty"`
+	FiapErrorType string      `json:"fiap_error_type,omitempty"`
+	FiapError     string      `json:"fiap_error,omitempty"`
+	Values        int         `json:"values"`
+	TLS           *tlsDetails `json:"tls,omitempty"`
+	Response      string      `json:"response,omitempty"`
+	Error         string      `json:"error,omitempty"`
+	// Endpoints are the health of the endpoints when standby endpoints are configured.
+	Endpoints []endpointStatus `json:"endpoints,omitempty"`
+}
+
+type tlsDetails struct {
+	Version          string               `json:"version"`
+	CipherSuite      string               `json:"cipher_suite"`
+	ServerName       string               `json:"server_name,omitempty"`
+	PeerCertificates []certificateDetails `json:"peer_certificates,omitempty"`
+}
+
+type certificateDetails struct {
+	Subject  string    `json:"subject"`
+	Issuer   string    `json:"issuer"`
+	NotAfter time.Time `json:"not_after"`
+}
+
+// CheckHealth fetches the latest value of the probe point with the FETCH protocol.
+// A FIAP error fails the check only when the probe point is configured.
+// With standby endpoints, the endpoints are probed in the order of the failover until one of them passes.
+func (cli *ClientImpl) CheckHealth(ctx context.Context) (*backend.CheckHealthResult, error) {
+	if cli.endpoints == nil {
+		status, message, details := cli.probe(ctx, cli.Settings.Url)
+		return healthResult(status, message, details), nil
+	}
+
+	var status backend.HealthStatus
+	var message string
+	var details *healthDetails
+	for i, ep := range cli.endpoints.candidates() {
+		epStatus, epMessage, epDetails := cli.probe(ctx, ep.url)
+		if epStatus == backend.HealthStatusOk {
+			cli.endpoints.markHealthy(ep)
+		} else {
+			cli.endpoints.markFailed(ep, errors.New(epMessage))
+		}
+		// report the first endpoint unless another one passes.
+		if i == 0 || epStatus == backend.HealthStatusOk {
+			status, message, details = epStatus, epMessage, epDetails
+		}
+		if epStatus == backend.HealthStatusOk {
+			break
+		}
+	}
+	details.Endpoints = cli.endpoints.statuses()
+	if status == backend.HealthStatusOk && details.URL != cli.Settings.Url {
+		message = fmt.Sprintf("Data source is working on the standby endpoint %s (%d ms)", details.URL, details.LatencyMs)
+	}
+	return healthResult(status, message, details), nil
+}
+
+// probe fetches the latest value of the probe point from the endpoint, and returns the health of it.
+func (cli *ClientImpl) probe(ctx context.Context, url string) (backend.HealthStatus, string, *healthDetails) {
+	backend.Logger.Debug("Start to check health", "connectionURL", url)
+	details := &healthDetails{URL: url, ProbePointID: cli.Settings.HealthProbePointID}
+	if details.ProbePointID == "" {
+		details.ProbePointID = defaultProbePointID
+	}
+	probe := &FetchClient{
+		ConnectionURL:   url,
+		HTTPClient:      cli.HTTPClient,
+		RequestHeaderFn: cli.RequestHeaderFn,
+		ctx:             ctx,
+		responseFn: func(resp *http.Response, body []byte) {
+			details.StatusCode = resp.StatusCode
+			details.TLS = tlsDetailsOf(resp.TLS)
+			if len(body) > maxHealthResponseSize {
+				body = body[:maxHealthResponseSize]
+			}
+			details.Response = string(body)
+		},
+	}
+
+	start := time.Now()
+	key := fiapmodel.UserInputKey{ID: details.ProbePointID, MinMaxIndicator: fiapmodel.SelectTypeMaximum}
+	httpResponse, queryRS, err := probe.query([]fiapmodel.UserInputKey{key}, nil)
+	details.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		backend.Logger.Error("Failed to check health", "error", err, "details", details)
+		details.Error = err.Error()
+		message := "Failed to check health. Please see logs for details."
+		if details.StatusCode >= http.StatusBadRequest {
+			message = fmt.Sprintf("URL returns status code %d. Please see logs for details.", details.StatusCode)
+		}
+		return backend.HealthStatusError, message, details
+	}
+	_, points, _, fiapErr, err := processQueryRS(httpResponse, queryRS)
+	if err != nil {
+		backend.Logger.Error("URL returns invalid FIAP response", "error", err, "details", details)
+		details.Error = err.Error()
+		return backend.HealthStatusError, "URL does not return a FIAP response. Please see logs for details.", details
+	}
+	if fiapErr != nil {
+		details.FiapErrorType, details.FiapError = fiapErr.Type, fiapErr.Value
+		if cli.Settings.HealthProbePointID != "" {
+			backend.Logger.Error("Probe point returns FIAP error", "details", details)
+			return backend.HealthStatusError, fmt.Sprintf("Probe point returns FIAP error %s: %s", fiapErr.Type, fiapErr.Value), details
+		}
+	}
+	details.Values = len(points[details.ProbePointID])
+
+	backend.Logger.Debug("Succeed to check health", "details", details)
+	return backend.HealthStatusOk, fmt.Sprintf("Data source is working (%d ms)", details.LatencyMs), details
+}
+
+// probeEndpoint returns the error when the endpoint does not pass the probe of the health check.
+func (cli *ClientImpl) probeEndpoint(ctx context.Context, ep *endpoint) error {
+	if status, message, _ := cli.probe(ctx, ep.url); status != backend.HealthStatusOk {
+		return errors.New(message)
+	}
+	return nil
+}
+
+func healthResult(status backend.HealthStatus, message string, details *healthDetails) *backend.CheckHealthResult {
+	result := &backend.CheckHealthResult{Status: status, Message: message}
+	if jsonDetails, err := json.Marshal(details); err == nil {
+		result.JSONDetails = jsonDetails
+	}
+	return result
+}
+
+// tlsDetailsOf returns the details of the TLS connection, or nil when the connection is not TLS.
+func tlsDetailsOf(state *tls.ConnectionState) *tlsDetails {
+	if state == nil {
+		return nil
+	}
+	details := &tlsDetails{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  state.ServerName,
+	}
+	for _, cert := range state.PeerCertificates {
+		details.PeerCertificates = append(details.PeerCertificates, certificateDetails{
+			Subject:  cert.Subject.String(),
+			Issuer:   cert.Issuer.String(),
+			NotAfter: cert.NotAfter,
+		})
+	}
+	return details
+}
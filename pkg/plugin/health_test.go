@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestClientImplCheckHealth(t *testing.T) {
+	checkHealth := func(t *testing.T, settings *dsmodel.FiapDatasourceSettings) (*backend.CheckHealthResult, *healthDetails) {
+		t.Helper()
+		cli, err := CreateFiapApiClient(settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := cli.CheckHealth(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		details := &healthDetails{}
+		if err := json.Unmarshal(result.JSONDetails, details); err != nil {
+			t.Fatalf("invalid details %s: %v", string(result.JSONDetails), err)
+		}
+		return result, details
+	}
+	fiapError := fiapErrorResponse("POINT_NOT_FOUND")
+
+	t.Run("Normal", func(t *testing.T) {
+		t.Run("ProbePoint", func(t *testing.T) {
+			server := newFakeFiapServer(t)
+			result, details := checkHealth(t, &dsmodel.FiapDatasourceSettings{Url: server.URL, HealthProbePointID: "id_a"})
+			if result.Status != backend.HealthStatusOk {
+				t.Errorf("expected status is %v but %v: %s", backend.HealthStatusOk, result.Status, result.Message)
+			}
+			if requests := server.requests(); len(requests) != 1 || !strings.Contains(requests[0].body, `id="id_a"`) {
+				t.Errorf("expected a query of the probe point but %v", requests)
+			}
+			if details.StatusCode != http.StatusOK || details.Values != 1 || details.TLS != nil || !strings.Contains(details.Response, "queryRS") {
+				t.Errorf("unexpected details %+v", details)
+			}
+		})
+		t.Run("DefaultProbeWithFiapError", func(t *testing.T) {
+			server := newFakeFiapServer(t, withResponses(fiapError))
+			result, details := checkHealth(t, &dsmodel.FiapDatasourceSettings{Url: server.URL})
+			if result.Status != backend.HealthStatusOk {
+				t.Errorf("expected status is %v but %v: %s", backend.HealthStatusOk, result.Status, result.Message)
+			}
+			if details.ProbePointID != defaultProbePointID || details.FiapErrorType != "POINT_NOT_FOUND" {
+				t.Errorf("unexpected details %+v", details)
+			}
+		})
+		t.Run("TLS", func(t *testing.T) {
+			server := newFakeFiapServer(t, withTLS(nil))
+			result, details := checkHealth(t, &dsmodel.FiapDatasourceSettings{Url: server.URL, TLSSkipVerify: true})
+			if result.Status != backend.HealthStatusOk {
+				t.Errorf("expected status is %v but %v: %s", backend.HealthStatusOk, result.Status, result.Message)
+			}
+			if details.TLS == nil || details.TLS.Version == "" || details.TLS.CipherSuite == "" || len(details.TLS.PeerCertificates) == 0 {
+				t.Errorf("expected details of the TLS connection but %+v", details.TLS)
+			}
+		})
+	})
+	t.Run("Error", func(t *testing.T) {
+		t.Run("ProbePointWithFiapError", func(t *testing.T) {
+			server := newFakeFiapServer(t, withResponses(fiapError))
+			result, details := checkHealth(t, &dsmodel.FiapDatasourceSettings{Url: server.URL, HealthProbePointID: "id_a"})
+			if result.Status != backend.HealthStatusError || !strings.Contains(result.Message, "POINT_NOT_FOUND") {
+				t.Errorf("expected status is %v with the FIAP error but %v: %s", backend.HealthStatusError, result.Status, result.Message)
+			}
+			if details.FiapErrorType != "POINT_NOT_FOUND" || details.FiapError != "test error" {
+				t.Errorf("unexpected details %+v", details)
+			}
+		})
+		t.Run("StatusCode", func(t *testing.T) {
+			server := newFakeFiapServer(t, withResponses(fakeResponse{status: http.StatusServiceUnavailable, body: "maintenance"}))
+			result, details := checkHealth(t, &dsmodel.FiapDatasourceSettings{Url: server.URL})
+			if result.Status != backend.HealthStatusError || !strings.Contains(result.Message, "503") {
+				t.Errorf("expected status is %v with the status code but %v: %s", backend.HealthStatusError, result.Status, result.Message)
+			}
+			if details.StatusCode != http.StatusServiceUnavailable || details.Response != "maintenance" || details.Error == "" {
+				t.Errorf("unexpected details %+v", details)
+			}
+		})
+		t.Run("NotFiap", func(t *testing.T) {
+			server := newFakeFiapServer(t, withBody("<html><body>It works!</body></html>"))
+			if result, _ := checkHealth(t, &dsmodel.FiapDatasourceSettings{Url: server.URL}); result.Status != backend.HealthStatusError {
+				t.Errorf("expected status is %v but %v", backend.HealthStatusError, result.Status)
+			}
+		})
+		t.Run("Unreachable", func(t *testing.T) {
+			server := newFakeFiapServer(t)
+			server.Close()
+			result, details := checkHealth(t, &dsmodel.FiapDatasourceSettings{Url: server.URL})
+			if result.Status != backend.HealthStatusError {
+				t.Errorf("expected status is %v but %v", backend.HealthStatusError, result.Status)
+			}
+			if details.StatusCode != 0 || details.Error == "" {
+				t.Errorf("unexpected details %+v", details)
+			}
+		})
+	})
+}
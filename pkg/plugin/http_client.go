@@ -0,0 +1,142 @@
+package plugin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/cockroachdb/errors"
+)
+
+// newHTTPClient returns the HTTP client of the timeouts, the TLS and the proxy configuration in the settings.
+// The connect timeout bounds the dial and the TLS handshake, and the read timeout bounds the wait for the response headers.
+// Reading the response body is not bounded by them but by the context of the request,
+// so that a large response of paging is not cut off while it is transferred.
+func newHTTPClient(settings *dsmodel.FiapDatasourceSettings) (*http.Client, error) {
+	connectTimeout, readTimeout, err := settings.Timeouts()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := newTLSConfig(settings)
+	if err != nil {
+		return nil, errors.Wrap(err, "tls")
+	}
+	proxyURL, err := parseProxyURL(settings)
+	if err != nil {
+		return nil, errors.Wrap(err, "proxy")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+	transport.ResponseHeaderTimeout = readTimeout
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+	if proxyURL != nil {
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	return &http.Client{Transport: transport}, nil
+}
+
+// parseProxyURL returns the proxy URL with the credentials of the settings, or nil when the proxy is not set.
+func parseProxyURL(settings *dsmodel.FiapDatasourceSettings) (*url.URL, error) {
+	if settings.ProxyURL == "" {
+		return nil, nil
+	}
+	proxyURL, err := url.Parse(settings.ProxyURL)
+	if err != nil {
+		return nil, err
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, errors.Newf("unsupported scheme of proxy url '%s'", settings.ProxyURL)
+	}
+	if proxyURL.Host == "" {
+		return nil, errors.Newf("proxy url '%s' has no host", settings.ProxyURL)
+	}
+	if settings.ProxyUser != "" {
+		proxyURL.User = url.UserPassword(settings.ProxyUser, settings.Secure.ProxyPassword)
+	}
+	return proxyURL, nil
+}
+
+// newRequestHeaderFn returns the function setting the custom headers and the authentication of the settings
+// on each request, or nil when there is nothing to set. The authentication overrides a custom Authorization header.
+func newRequestHeaderFn(settings *dsmodel.FiapDatasourceSettings) (func(http.Header), error) {
+	customHeaders := settings.CustomHeaders()
+	headers := make(http.Header, len(customHeaders))
+	for name, value := range customHeaders {
+		if !isHeaderName(name) {
+			return nil, errors.Newf("invalid custom header name '%s'", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, errors.Newf("custom header '%s' has a line break", name)
+		}
+		headers.Set(name, value)
+	}
+	authorize := authorizeFn(settings)
+	if len(headers) == 0 && authorize == nil {
+		return nil, nil
+	}
+	return func(header http.Header) {
+		for name, values := range headers {
+			header[name] = values
+		}
+		if authorize != nil {
+			authorize(header)
+		}
+	}, nil
+}
+
+// isHeaderName returns whether the name is a token of RFC 7230.
+func isHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r > 0x7e || r <= 0x20 || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// newTLSConfig returns the TLS configuration of the settings, or nil when nothing is configured.
+func newTLSConfig(settings *dsmodel.FiapDatasourceSettings) (*tls.Config, error) {
+	secure := settings.Secure
+	if secure.TLSCACert == "" && secure.TLSClientCert == "" && secure.TLSClientKey == "" && settings.TLSServerName == "" && !settings.TLSSkipVerify {
+		return nil, nil
+	}
+	config := &tls.Config{
+		ServerName:         settings.TLSServerName,
+		InsecureSkipVerify: settings.TLSSkipVerify,
+	}
+	if secure.TLSCACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(secure.TLSCACert)) {
+			return nil, errors.New("CA certificate has no valid PEM certificates")
+		}
+		config.RootCAs = pool
+	}
+	if secure.TLSClientCert != "" || secure.TLSClientKey != "" {
+		if secure.TLSClientCert == "" || secure.TLSClientKey == "" {
+			return nil, errors.New("client certificate and client key must be set together")
+		}
+		cert, err := tls.X509KeyPair([]byte(secure.TLSClientCert), []byte(secure.TLSClientKey))
+		if err != nil {
+			return nil, errors.Wrap(err, "client certificate")
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
@@ -0,0 +1,213 @@
+package plugin
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// testCertificate is a certificate and its key encoded in PEM.
+type testCertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM string
+	keyPEM  string
+}
+
+// newTestCertificate issues the certificate by the parent, or a self-signed CA certificate when the parent is nil.
+func newTestCertificate(t *testing.T, template *x509.Certificate, parent *testCertificate) *testCertificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	issuer, signer := template, key
+	if parent != nil {
+		issuer, signer = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCertificate{
+		cert:    cert,
+		key:     key,
+		certPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		keyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+	}
+}
+
+func TestCreateFiapApiClientTLS(t *testing.T) {
+	ca := newTestCertificate(t, &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil)
+	serverCert := newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "fiap.example"},
+		DNSNames:     []string{"fiap.example"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+	clientCert := newTestCertificate(t, &x509.Certificate{
+		SerialNumber: big.NewInt(3),
+		Subject:      pkix.Name{CommonName: "grafana"},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+
+	serverKeyPair, err := tls.X509KeyPair([]byte(serverCert.certPEM), []byte(serverCert.keyPEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+	server := newFakeFiapServer(t, withTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverKeyPair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}))
+
+	checkHealth := func(settings *dsmodel.FiapDatasourceSettings) backend.HealthStatus {
+		t.Helper()
+		settings.Url = server.URL
+		cli, err := CreateFiapApiClient(settings)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result, err := cli.CheckHealth(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result.Status
+	}
+	mutualTLS := dsmodel.FiapSecureSettings{TLSCACert: ca.certPEM, TLSClientCert: clientCert.certPEM, TLSClientKey: clientCert.keyPEM}
+
+	t.Run("Normal", func(t *testing.T) {
+		t.Run("MutualTLS", func(t *testing.T) {
+			if status := checkHealth(&dsmodel.FiapDatasourceSettings{TLSServerName: "fiap.example", Secure: mutualTLS}); status != backend.HealthStatusOk {
+				t.Errorf("expected status is %v but %v", backend.HealthStatusOk, status)
+			}
+		})
+		t.Run("SkipVerify", func(t *testing.T) {
+			secure := dsmodel.FiapSecureSettings{TLSClientCert: clientCert.certPEM, TLSClientKey: clientCert.keyPEM}
+			if status := checkHealth(&dsmodel.FiapDatasourceSettings{TLSSkipVerify: true, Secure: secure}); status != backend.HealthStatusOk {
+				t.Errorf("expected status is %v but %v", backend.HealthStatusOk, status)
+			}
+		})
+	})
+	t.Run("Error", func(t *testing.T) {
+		t.Run("ServerNameMismatch", func(t *testing.T) {
+			if status := checkHealth(&dsmodel.FiapDatasourceSettings{Secure: mutualTLS}); status != backend.HealthStatusError {
+				t.Errorf("expected status is %v but %v", backend.HealthStatusError, status)
+			}
+		})
+		t.Run("NoClientCertificate", func(t *testing.T) {
+			secure := dsmodel.FiapSecureSettings{TLSCACert: ca.certPEM}
+			if status := checkHealth(&dsmodel.FiapDatasourceSettings{TLSServerName: "fiap.example", Secure: secure}); status != backend.HealthStatusError {
+				t.Errorf("expected status is %v but %v", backend.HealthStatusError, status)
+			}
+		})
+		t.Run("InvalidConfiguration", func(t *testing.T) {
+			for name, secure := range map[string]dsmodel.FiapSecureSettings{
+				"CACert":        {TLSCACert: "not a certificate"},
+				"KeyOnly":       {TLSClientKey: clientCert.keyPEM},
+				"KeyMismatched": {TLSClientCert: clientCert.certPEM, TLSClientKey: ca.keyPEM},
+			} {
+				if _, err := CreateFiapApiClient(&dsmodel.FiapDatasourceSettings{Url: server.URL, Secure: secure}); err == nil {
+					t.Errorf("expected error of %s but nil", name)
+				}
+			}
+		})
+	})
+}
+
+func TestCreateFiapApiClientProxy(t *testing.T) {
+	fromTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	toTime := time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC)
+	proxy := newFakeFiapServer(t)
+
+	t.Run("Normal", func(t *testing.T) {
+		cli, err := CreateFiapApiClient(&dsmodel.FiapDatasourceSettings{
+			Url:         "http://fiap.example/axis2/services/FIAPStorage",
+			ProxyURL:    proxy.URL,
+			ProxyUser:   "proxy_user",
+			HeaderNames: map[string]string{"1": "X-Tenant-Id", "2": "Authorization"},
+			AuthType:    dsmodel.BearerAuth,
+			Secure: dsmodel.FiapSecureSettings{BearerToken: "token", ProxyPassword: "proxy_pass",
+				HeaderValues: map[string]string{"1": "plant1", "2": "Bearer overridden"}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := &backend.DataResponse{}
+		if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, []dsmodel.PointID{{Value: "id_a"}}, &backend.DataQuery{RefID: "A"}, nil); err != nil {
+			t.Fatal(err)
+		}
+		if result, err := cli.CheckHealth(context.Background()); err != nil {
+			t.Fatal(err)
+		} else if result.Status != backend.HealthStatusOk {
+			t.Errorf("expected status is %v but %v: %s", backend.HealthStatusOk, result.Status, result.Message)
+		}
+
+		requests := proxy.requests()
+		if len(requests) != 2 {
+			t.Fatalf("expected 2 requests through the proxy but %d", len(requests))
+		}
+		expectedProxyAuth := "Basic " + base64.StdEncoding.EncodeToString([]byte("proxy_user:proxy_pass"))
+		for i, r := range requests {
+			if r.host != "fiap.example" {
+				t.Errorf("expected host of request[%d] is %s but %s", i, "fiap.example", r.host)
+			}
+			if actual := r.header.Get("Proxy-Authorization"); actual != expectedProxyAuth {
+				t.Errorf("expected Proxy-Authorization of request[%d] is '%s' but '%s'", i, expectedProxyAuth, actual)
+			}
+			if actual := r.header.Get("X-Tenant-Id"); actual != "plant1" {
+				t.Errorf("expected X-Tenant-Id of request[%d] is '%s' but '%s'", i, "plant1", actual)
+			}
+			if actual := r.header.Get("Authorization"); actual != "Bearer token" {
+				t.Errorf("expected Authorization of request[%d] is '%s' but '%s'", i, "Bearer token", actual)
+			}
+		}
+	})
+	t.Run("Error", func(t *testing.T) {
+		for name, settings := range map[string]dsmodel.FiapDatasourceSettings{
+			"ProxyScheme": {ProxyURL: "ftp://proxy.example:21"},
+			"ProxyHost":   {ProxyURL: "http://"},
+			"HeaderName":  {HeaderNames: map[string]string{"1": "X Tenant"}},
+			"HeaderLineBreak": {HeaderNames: map[string]string{"1": "X-Tenant-Id"},
+				Secure: dsmodel.FiapSecureSettings{HeaderValues: map[string]string{"1": "plant1\r\nX-Injected: 1"}}},
+		} {
+			settings.Url = "http://fiap.example"
+			if _, err := CreateFiapApiClient(&settings); err == nil {
+				t.Errorf("expected error of %s but nil", name)
+			}
+		}
+	})
+}
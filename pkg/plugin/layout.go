@@ -0,0 +1,145 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// joinFrames outer-joins the frames into a wide frame named by the refID, which has a time field shared by all points.
+// Timestamps are truncated by the rounding in the location when it is not nil, and the last non-null value wins when
+// a frame has multiple values in a row. The fields become nullable, and the notices of the frames are kept.
+func joinFrames(frames data.Frames, refID string, rounding *dsmodel.Interval, location *time.Location) *data.Frame {
+	roundTime := func(dt time.Time) time.Time {
+		if rounding == nil {
+			return dt
+		}
+		return rounding.Truncate(dt.In(location))
+	}
+
+	// collect the distinct timestamps of all frames.
+	rowOf := make(map[int64]int)
+	times := make([]time.Time, 0)
+	for _, frame := range frames {
+		if len(frame.Fields) == 0 || frame.Fields[0].Type() != data.FieldTypeTime {
+			continue
+		}
+		for i := 0; i < frame.Rows(); i++ {
+			dt := roundTime(frame.Fields[0].At(i).(time.Time))
+			if _, ok := rowOf[dt.UnixNano()]; !ok {
+				rowOf[dt.UnixNano()] = 0
+				times = append(times, dt)
+			}
+		}
+	}
+	sort.Slice(times, func(i, j int) bool {
+		return times[i].Before(times[j])
+	})
+	for i, dt := range times {
+		rowOf[dt.UnixNano()] = i
+	}
+
+	joined := data.NewFrame(refID, data.NewField("time", nil, times))
+	joined.Meta = &data.FrameMeta{Type: data.FrameTypeTimeSeriesWide, TypeVersion: data.FrameTypeVersion{0, 1}}
+	for _, frame := range frames {
+		if frame.Meta != nil {
+			joined.Meta.Notices = append(joined.Meta.Notices, frame.Meta.Notices...)
+		}
+		if len(frame.Fields) == 0 || frame.Fields[0].Type() != data.FieldTypeTime {
+			continue
+		}
+		timeField := frame.Fields[0]
+		for _, field := range frame.Fields[1:] {
+			column := data.NewFieldFromFieldType(field.Type().NullableType(), len(times))
+			column.Name, column.Labels, column.Config = field.Name, field.Labels, field.Config
+			for i := 0; i < field.Len(); i++ {
+				if value, ok := field.ConcreteAt(i); ok {
+					column.SetConcrete(rowOf[roundTime(timeField.At(i).(time.Time)).UnixNano()], value)
+				}
+			}
+			joined.Fields = append(joined.Fields, column)
+		}
+	}
+	return joined
+}
+
+// longRow is a value of a point in the long frame.
+// number is the value as a number, which is nil when the value is null or not a number.
+type longRow struct {
+	time    time.Time
+	pointID string
+	number  *float64
+	text    *string
+	labels  data.Labels
+}
+
+// longFrameOf converts the frames into a long frame named by the refID, which has the columns time, point_id and value
+// and a string column for each label of the points, sorted by time.
+// Values are nullable numbers when all points are numbers, otherwise strings.
+// Points without values, such as the ones without samples, do not decide the type.
+// Only the first value field of each frame is converted, so rejected values are left out.
+func longFrameOf(frames data.Frames, refID string) *data.Frame {
+	rows := make([]longRow, 0)
+	labelKeys := make([]string, 0)
+	hasLabelKey := make(map[string]bool)
+	numeric := true
+	long := data.NewFrame(refID)
+	long.Meta = &data.FrameMeta{Type: data.FrameTypeTimeSeriesLong, TypeVersion: data.FrameTypeVersion{0, 1}}
+	for _, frame := range frames {
+		if frame.Meta != nil {
+			long.Meta.Notices = append(long.Meta.Notices, frame.Meta.Notices...)
+		}
+		if len(frame.Fields) < 2 || frame.Fields[0].Type() != data.FieldTypeTime {
+			continue
+		}
+		timeField, valueField := frame.Fields[0], frame.Fields[1]
+		numeric = numeric && (valueField.Type().Numeric() || isEmptyField(valueField))
+		for key := range valueField.Labels {
+			if !hasLabelKey[key] {
+				hasLabelKey[key] = true
+				labelKeys = append(labelKeys, key)
+			}
+		}
+		for i := 0; i < valueField.Len(); i++ {
+			row := longRow{time: timeField.At(i).(time.Time), pointID: valueField.Name, labels: valueField.Labels}
+			if value, ok := valueField.ConcreteAt(i); ok {
+				text := fmt.Sprint(value)
+				row.text = &text
+				if valueField.Type().Numeric() {
+					row.number, _ = valueField.NullableFloatAt(i)
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		return rows[i].time.Before(rows[j].time)
+	})
+	sort.Strings(labelKeys)
+
+	times := make([]time.Time, len(rows))
+	pointIDs := make([]string, len(rows))
+	numbers := make([]*float64, len(rows))
+	texts := make([]*string, len(rows))
+	for i, row := range rows {
+		times[i], pointIDs[i], numbers[i], texts[i] = row.time, row.pointID, row.number, row.text
+	}
+	long.Fields = append(long.Fields, data.NewField("time", nil, times), data.NewField("point_id", nil, pointIDs))
+	if numeric {
+		long.Fields = append(long.Fields, data.NewField("value", nil, numbers))
+	} else {
+		long.Fields = append(long.Fields, data.NewField("value", nil, texts))
+	}
+	for _, key := range labelKeys {
+		values := make([]string, len(rows))
+		for i, row := range rows {
+			values[i] = row.labels[key]
+		}
+		long.Fields = append(long.Fields, data.NewField(key, nil, values))
+	}
+	return long
+}
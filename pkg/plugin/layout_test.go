@@ -0,0 +1,137 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestJoinFrames(t *testing.T) {
+	baseTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	newFrames := func() data.Frames {
+		frameA := data.NewFrame("A:id_a",
+			data.NewField("time", nil, []time.Time{baseTime, baseTime.Add(time.Minute)}),
+			data.NewField("id_a", nil, []float64{1, 2}).SetConfig(&data.FieldConfig{DisplayNameFromDS: "alias"}),
+		)
+		frameB := data.NewFrame("A:id_b",
+			data.NewField("time", nil, []time.Time{baseTime.Add(time.Minute + 300*time.Millisecond), baseTime.Add(2 * time.Minute)}),
+			data.NewField("id_b", data.Labels{"path": "set"}, []string{"on", "off"}),
+		)
+		frameB.AppendNotices(data.Notice{Severity: data.NoticeSeverityWarning, Text: "truncated"})
+		return data.Frames{frameA, frameB}
+	}
+	t.Run("Normal", func(t *testing.T) {
+		t.Run("OuterJoin", func(t *testing.T) {
+			joined := joinFrames(newFrames(), "A", nil, time.UTC)
+			if joined.Name != "A" || joined.Meta.Type != data.FrameTypeTimeSeriesWide {
+				t.Errorf("expected wide frame named %s but %s of %s", "A", joined.Name, joined.Meta.Type)
+			}
+			if joined.Rows() != 4 || len(joined.Fields) != 3 {
+				t.Fatalf("expected 4 rows and 3 fields but %d rows and %d fields", joined.Rows(), len(joined.Fields))
+			}
+			if actual := joined.Fields[1].At(2).(*float64); actual != nil {
+				t.Errorf("expected value of id_a at row 2 is nil but %v", *actual)
+			}
+			if actual := joined.Fields[2].At(2).(*string); actual == nil || *actual != "on" {
+				t.Errorf("expected value of id_b at row 2 is %s but %v", "on", actual)
+			}
+			if joined.Fields[1].Config == nil || joined.Fields[1].Config.DisplayNameFromDS != "alias" || joined.Fields[2].Labels["path"] != "set" {
+				t.Errorf("expected config and labels are kept but %v, %v", joined.Fields[1].Config, joined.Fields[2].Labels)
+			}
+			if len(joined.Meta.Notices) != 1 {
+				t.Errorf("expected notices are kept but %v", joined.Meta.Notices)
+			}
+		})
+		t.Run("Rounding", func(t *testing.T) {
+			rounding, err := dsmodel.ParseInterval("1s")
+			if err != nil {
+				t.Fatal(err)
+			}
+			joined := joinFrames(newFrames(), "A", &rounding, time.UTC)
+			if joined.Rows() != 3 {
+				t.Fatalf("expected rows are %d but %d", 3, joined.Rows())
+			}
+			if actual := joined.Fields[1].At(1).(*float64); actual == nil || *actual != 2 {
+				t.Errorf("expected value of id_a at row 1 is %v but %v", 2, actual)
+			}
+			if actual := joined.Fields[2].At(1).(*string); actual == nil || *actual != "on" {
+				t.Errorf("expected value of id_b at row 1 is %s but %v", "on", actual)
+			}
+		})
+	})
+}
+
+func TestLongFrameOf(t *testing.T) {
+	baseTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	newFrames := func(valuesB interface{}) data.Frames {
+		frameA := data.NewFrame("A:id_a",
+			data.NewField("time", nil, []time.Time{baseTime, baseTime.Add(2 * time.Minute)}),
+			data.NewField("id_a", nil, []float64{1, 2}),
+		)
+		frameB := data.NewFrame("A:id_b",
+			data.NewField("time", nil, []time.Time{baseTime.Add(time.Minute)}),
+			data.NewField("id_b", data.Labels{"path": "set"}, valuesB),
+			data.NewField("id_b"+rejectedFieldSuffix, nil, []*string{nil}),
+		)
+		return data.Frames{frameA, frameB}
+	}
+	t.Run("Normal", func(t *testing.T) {
+		t.Run("Numbers", func(t *testing.T) {
+			long := longFrameOf(newFrames([]*float64{nil}), "A")
+			if long.Name != "A" || long.Meta.Type != data.FrameTypeTimeSeriesLong {
+				t.Errorf("expected long frame named %s but %s of %s", "A", long.Name, long.Meta.Type)
+			}
+			if long.Rows() != 3 || len(long.Fields) != 4 {
+				t.Fatalf("expected 3 rows and 4 fields but %d rows and %d fields", long.Rows(), len(long.Fields))
+			}
+			expectedNames := []string{"time", "point_id", "value", "path"}
+			for i, expected := range expectedNames {
+				if long.Fields[i].Name != expected {
+					t.Errorf("expected name of field[%d] is %s but %s", i, expected, long.Fields[i].Name)
+				}
+			}
+			expectedPointIDs := []string{"id_a", "id_b", "id_a"}
+			expectedPaths := []string{"", "set", ""}
+			for i := range expectedPointIDs {
+				if actual := long.Fields[1].At(i).(string); actual != expectedPointIDs[i] {
+					t.Errorf("expected point_id[%d] is %s but %s", i, expectedPointIDs[i], actual)
+				}
+				if actual := long.Fields[3].At(i).(string); actual != expectedPaths[i] {
+					t.Errorf("expected path[%d] is %s but %s", i, expectedPaths[i], actual)
+				}
+			}
+			if actual := long.Fields[2].At(1).(*float64); actual != nil {
+				t.Errorf("expected value[1] is nil but %v", *actual)
+			}
+			if actual := long.Fields[2].At(2).(*float64); actual == nil || *actual != 2 {
+				t.Errorf("expected value[2] is %v but %v", 2, actual)
+			}
+		})
+		t.Run("Strings", func(t *testing.T) {
+			long := longFrameOf(newFrames([]string{"on"}), "A")
+			expected := []string{"1", "on", "2"}
+			for i := range expected {
+				if actual := long.Fields[2].At(i).(*string); actual == nil || *actual != expected[i] {
+					t.Errorf("expected value[%d] is %s but %v", i, expected[i], actual)
+				}
+			}
+		})
+		t.Run("EmptyPoint", func(t *testing.T) {
+			// the string field of the point without samples does not turn the values into strings.
+			frames := append(newFrames([]*float64{pointerOf(3.0)}), data.NewFrame("A:id_empty",
+				data.NewField("time", nil, []time.Time{}),
+				data.NewField("id_empty", nil, []string{}),
+			))
+			long := longFrameOf(frames, "A")
+			if long.Rows() != 3 {
+				t.Fatalf("expected rows are %d but %d", 3, long.Rows())
+			}
+			if actual, ok := long.Fields[2].At(1).(*float64); !ok || actual == nil || *actual != 3 {
+				t.Errorf("expected value[1] is %v but %v", 3, long.Fields[2].At(1))
+			}
+		})
+	})
+}
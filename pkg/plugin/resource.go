@@ -0,0 +1,195 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/sios/fiap/pkg/model"
+)
+
+// pointTreeCacheTTL is how long the children of a point set are cached.
+const pointTreeCacheTTL = 5 * time.Minute
+
+// maxPointTreeCacheEntries is the maximum number of point sets whose children are cached.
+const maxPointTreeCacheEntries = 1000
+
+// defaultSearchLimit is the maximum number of IDs returned by the search endpoint without the limit parameter.
+const defaultSearchLimit = 100
+
+// pointTreeResponse is the body of the resource endpoints.
+type pointTreeResponse struct {
+	PointSetIDs []string `json:"point_set_ids"`
+	PointIDs    []string `json:"point_ids"`
+}
+
+type resourceErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// pointTreeCache holds the children of the point sets fetched by the resource endpoints.
+type pointTreeCache struct {
+	mu      sync.Mutex
+	entries map[string]pointTreeCacheEntry
+}
+
+type pointTreeCacheEntry struct {
+	pointSetIDs []string
+	pointIDs    []string
+	expiresAt   time.Time
+}
+
+func (c *pointTreeCache) get(pointSetID string) (pointTreeCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[pointSetID]
+	if !ok || timeNow().After(entry.expiresAt) {
+		return pointTreeCacheEntry{}, false
+	}
+	return entry, true
+}
+
+// set caches the children of the point set. The expired entries are dropped, and the entry expiring first
+// is evicted when the cache is full.
+func (c *pointTreeCache) set(pointSetID string, pointSetIDs []string, pointIDs []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]pointTreeCacheEntry)
+	}
+	now := timeNow()
+	for id, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, id)
+		}
+	}
+	if _, ok := c.entries[pointSetID]; !ok && len(c.entries) >= maxPointTreeCacheEntries {
+		oldestID := ""
+		for id, entry := range c.entries {
+			if oldestID == "" || entry.expiresAt.Before(c.entries[oldestID].expiresAt) {
+				oldestID = id
+			}
+		}
+		delete(c.entries, oldestID)
+	}
+	c.entries[pointSetID] = pointTreeCacheEntry{pointSetIDs: pointSetIDs, pointIDs: pointIDs, expiresAt: now.Add(pointTreeCacheTTL)}
+}
+
+// search returns the cached IDs of point sets and points which start with the prefix.
+func (c *pointTreeCache) search(prefix string, limit int) ([]string, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	pointSetIDs, pointIDs := make(map[string]bool), make(map[string]bool)
+	now := timeNow()
+	for id, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			continue
+		}
+		pointSetIDs[id] = true
+		for _, childID := range entry.pointSetIDs {
+			pointSetIDs[childID] = true
+		}
+		for _, childID := range entry.pointIDs {
+			pointIDs[childID] = true
+		}
+	}
+	return filterByPrefix(sortedKeys(pointSetIDs), prefix, limit), filterByPrefix(sortedKeys(pointIDs), prefix, limit)
+}
+
+// CallResource handles the requests from the query editor to browse the point tree.
+//
+//   - GET points?id=<point set ID>[&prefix=<prefix>]: lists the point sets and points directly under the point set.
+//   - GET search?prefix=<prefix>[&limit=<limit>]: searches the point sets and points browsed so far by prefix.
+func (d *Datasource) CallResource(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	ctxLogger := backend.Logger.FromContext(ctx)
+	ctxLogger.Debug("Start CallResource in fiap datasource", "path", req.Path, "url", req.URL)
+
+	if req.Method != http.MethodGet {
+		return sendResourceResponse(sender, http.StatusMethodNotAllowed, resourceErrorResponse{Error: "method not allowed"})
+	}
+	reqURL, err := url.Parse(req.URL)
+	if err != nil {
+		return sendResourceResponse(sender, http.StatusBadRequest, resourceErrorResponse{Error: err.Error()})
+	}
+	params := reqURL.Query()
+
+	switch strings.Trim(req.Path, "/") {
+	case "points":
+		pointSetID := params.Get("id")
+		if pointSetID == "" {
+			return sendResourceResponse(sender, http.StatusBadRequest, resourceErrorResponse{Error: "parameter 'id' is required"})
+		}
+		entry, ok := d.pointTree.get(pointSetID)
+		if !ok {
+			pointSetIDs, pointIDs, err := d.Client.FetchChildren(ctx, pointSetID)
+			if err != nil {
+				ctxLogger.Error("Error fetch children of point set", "pointSetID", pointSetID, "error", err)
+				return sendResourceResponse(sender, fetchChildrenStatus(err), resourceErrorResponse{Error: err.Error()})
+			}
+			d.pointTree.set(pointSetID, pointSetIDs, pointIDs)
+			entry = pointTreeCacheEntry{pointSetIDs: pointSetIDs, pointIDs: pointIDs}
+		}
+		prefix := params.Get("prefix")
+		return sendResourceResponse(sender, http.StatusOK, pointTreeResponse{
+			PointSetIDs: filterByPrefix(entry.pointSetIDs, prefix, 0),
+			PointIDs:    filterByPrefix(entry.pointIDs, prefix, 0),
+		})
+	case "search":
+		limit := defaultSearchLimit
+		if rawLimit := params.Get("limit"); rawLimit != "" {
+			if limit, err = strconv.Atoi(rawLimit); err != nil || limit <= 0 {
+				return sendResourceResponse(sender, http.StatusBadRequest, resourceErrorResponse{Error: "parameter 'limit' must be a positive integer"})
+			}
+		}
+		pointSetIDs, pointIDs := d.pointTree.search(params.Get("prefix"), limit)
+		return sendResourceResponse(sender, http.StatusOK, pointTreeResponse{PointSetIDs: pointSetIDs, PointIDs: pointIDs})
+	default:
+		return sendResourceResponse(sender, http.StatusNotFound, resourceErrorResponse{Error: "not found"})
+	}
+}
+
+// fetchChildrenStatus returns the status of the error of FetchChildren. The errors of the ID are the client's,
+// and the others are of the FIAP server.
+func fetchChildrenStatus(err error) int {
+	switch {
+	case errors.Is(err, model.ErrPointNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, model.ErrNotPointSet):
+		return http.StatusBadRequest
+	default:
+		return http.StatusBadGateway
+	}
+}
+
+func sendResourceResponse(sender backend.CallResourceResponseSender, status int, body interface{}) error {
+	bytes, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return sender.Send(&backend.CallResourceResponse{
+		Status:  status,
+		Headers: map[string][]string{"Content-Type": {"application/json"}},
+		Body:    bytes,
+	})
+}
+
+// filterByPrefix returns the IDs starting with the prefix, at most limit IDs unless limit is 0.
+func filterByPrefix(ids []string, prefix string, limit int) []string {
+	filtered := make([]string, 0)
+	for _, id := range ids {
+		if limit > 0 && len(filtered) >= limit {
+			break
+		}
+		if strings.HasPrefix(id, prefix) {
+			filtered = append(filtered, id)
+		}
+	}
+	return filtered
+}
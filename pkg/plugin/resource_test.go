@@ -0,0 +1,183 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/sios/fiap/pkg/model"
+)
+
+type mockResourceSender struct {
+	response *backend.CallResourceResponse
+}
+
+func (s *mockResourceSender) Send(resp *backend.CallResourceResponse) error {
+	s.response = resp
+	return nil
+}
+
+func TestCallResource(t *testing.T) {
+	originalTimeNow := timeNow
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = originalTimeNow }()
+
+	fetchCount := 0
+	ds := Datasource{Client: &MockClient{
+		fetchChildrenFunc: func(pointSetID string) ([]string, []string, error) {
+			fetchCount++
+			switch pointSetID {
+			case "http://example.com/building1":
+				return []string{"http://example.com/building1/floor1", "http://example.com/building1/floor2"}, []string{"http://example.com/building1/power"}, nil
+			case "http://example.com/building1/floor2":
+				return []string{}, []string{"http://example.com/building1/floor2/temp", "http://example.com/building1/floor2/hum"}, nil
+			case "id_point":
+				return nil, nil, errors.Mark(errors.New("point id 'id_point' not provides point sets"), model.ErrNotPointSet)
+			case "id_unknown":
+				return nil, nil, errors.Mark(errors.New("point id 'id_unknown' is not found"), model.ErrPointNotFound)
+			default:
+				return nil, nil, errors.Newf("point id '%s' not provides point sets", pointSetID)
+			}
+		},
+	}}
+	callResource := func(method, path, rawURL string) (int, pointTreeResponse, string) {
+		sender := &mockResourceSender{}
+		if err := ds.CallResource(context.Background(), &backend.CallResourceRequest{Method: method, Path: path, URL: rawURL}, sender); err != nil {
+			t.Fatal(err)
+		}
+		var body pointTreeResponse
+		var errBody resourceErrorResponse
+		if sender.response.Status == http.StatusOK {
+			if err := json.Unmarshal(sender.response.Body, &body); err != nil {
+				t.Fatal(err)
+			}
+		} else if err := json.Unmarshal(sender.response.Body, &errBody); err != nil {
+			t.Fatal(err)
+		}
+		return sender.response.Status, body, errBody.Error
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		t.Run("Points", func(t *testing.T) {
+			status, body, _ := callResource(http.MethodGet, "points", "points?id=http%3A%2F%2Fexample.com%2Fbuilding1")
+			if status != http.StatusOK {
+				t.Fatalf("expected status is %d but %d", http.StatusOK, status)
+			}
+			if actual := strings.Join(body.PointSetIDs, ","); actual != "http://example.com/building1/floor1,http://example.com/building1/floor2" {
+				t.Errorf("unexpected point set IDs %s", actual)
+			}
+			if actual := strings.Join(body.PointIDs, ","); actual != "http://example.com/building1/power" {
+				t.Errorf("unexpected point IDs %s", actual)
+			}
+		})
+		t.Run("PointsWithPrefix", func(t *testing.T) {
+			status, body, _ := callResource(http.MethodGet, "points", "points?id=http%3A%2F%2Fexample.com%2Fbuilding1%2Ffloor2&prefix=http%3A%2F%2Fexample.com%2Fbuilding1%2Ffloor2%2Ft")
+			if status != http.StatusOK {
+				t.Fatalf("expected status is %d but %d", http.StatusOK, status)
+			}
+			if len(body.PointSetIDs) != 0 {
+				t.Errorf("expected no point set IDs but %v", body.PointSetIDs)
+			}
+			if actual := strings.Join(body.PointIDs, ","); actual != "http://example.com/building1/floor2/temp" {
+				t.Errorf("unexpected point IDs %s", actual)
+			}
+		})
+		t.Run("Cache", func(t *testing.T) {
+			fetchCount = 0
+			callResource(http.MethodGet, "points", "points?id=http%3A%2F%2Fexample.com%2Fbuilding1")
+			if fetchCount != 0 {
+				t.Errorf("expected fetch count is %d but %d", 0, fetchCount)
+			}
+			now = now.Add(pointTreeCacheTTL + time.Second)
+			callResource(http.MethodGet, "points", "points?id=http%3A%2F%2Fexample.com%2Fbuilding1")
+			if fetchCount != 1 {
+				t.Errorf("expected fetch count is %d but %d", 1, fetchCount)
+			}
+		})
+		t.Run("Search", func(t *testing.T) {
+			status, body, _ := callResource(http.MethodGet, "search", "search?prefix=http%3A%2F%2Fexample.com%2Fbuilding1%2Ff")
+			if status != http.StatusOK {
+				t.Fatalf("expected status is %d but %d", http.StatusOK, status)
+			}
+			// the children of floor2 are expired in the cache.
+			if actual := strings.Join(body.PointSetIDs, ","); actual != "http://example.com/building1/floor1,http://example.com/building1/floor2" {
+				t.Errorf("unexpected point set IDs %s", actual)
+			}
+			if len(body.PointIDs) != 0 {
+				t.Errorf("expected no point IDs but %v", body.PointIDs)
+			}
+		})
+		t.Run("SearchWithLimit", func(t *testing.T) {
+			status, body, _ := callResource(http.MethodGet, "search", "search?prefix=http&limit=1")
+			if status != http.StatusOK {
+				t.Fatalf("expected status is %d but %d", http.StatusOK, status)
+			}
+			if len(body.PointSetIDs) != 1 || len(body.PointIDs) != 1 {
+				t.Errorf("expected one point set ID and one point ID but %v and %v", body.PointSetIDs, body.PointIDs)
+			}
+		})
+	})
+	t.Run("Error", func(t *testing.T) {
+		cases := []struct {
+			name, method, path, url string
+			expectedStatus          int
+			expectedErr             string
+		}{
+			{"NoID", http.MethodGet, "points", "points", http.StatusBadRequest, "parameter 'id' is required"},
+			{"FetchFailed", http.MethodGet, "points", "points?id=id_a", http.StatusBadGateway, "point id 'id_a' not provides point sets"},
+			{"NotPointSet", http.MethodGet, "points", "points?id=id_point", http.StatusBadRequest, "point id 'id_point' not provides point sets"},
+			{"UnknownID", http.MethodGet, "points", "points?id=id_unknown", http.StatusNotFound, "point id 'id_unknown' is not found"},
+			{"InvalidLimit", http.MethodGet, "search", "search?limit=-1", http.StatusBadRequest, "parameter 'limit'"},
+			{"UnknownPath", http.MethodGet, "unknown", "unknown", http.StatusNotFound, "not found"},
+			{"InvalidMethod", http.MethodPost, "points", "points?id=id_a", http.StatusMethodNotAllowed, "method not allowed"},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				status, _, errMessage := callResource(c.method, c.path, c.url)
+				if status != c.expectedStatus {
+					t.Errorf("expected status is %d but %d", c.expectedStatus, status)
+				}
+				if !strings.Contains(errMessage, c.expectedErr) {
+					t.Errorf("expected error is %s but %s", c.expectedErr, errMessage)
+				}
+			})
+		}
+	})
+}
+
+func TestPointTreeCacheBound(t *testing.T) {
+	originalTimeNow := timeNow
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	timeNow = func() time.Time { return now }
+	defer func() { timeNow = originalTimeNow }()
+
+	cache := &pointTreeCache{}
+	for i := 0; i < maxPointTreeCacheEntries; i++ {
+		now = now.Add(time.Millisecond)
+		cache.set(fmt.Sprintf("id_%d", i), nil, nil)
+	}
+	cache.set("id_new", nil, nil)
+	if len(cache.entries) != maxPointTreeCacheEntries {
+		t.Errorf("expected %d entries but %d", maxPointTreeCacheEntries, len(cache.entries))
+	}
+	if _, ok := cache.get("id_0"); ok {
+		t.Error("expected the entry expiring first is evicted")
+	}
+	if _, ok := cache.get("id_new"); !ok {
+		t.Error("expected the new entry is cached")
+	}
+
+	// the expired entries are dropped on the next set.
+	now = now.Add(pointTreeCacheTTL + time.Second)
+	cache.set("id_later", nil, nil)
+	if len(cache.entries) != 1 {
+		t.Errorf("expected only the new entry but %d entries", len(cache.entries))
+	}
+}
@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	fiapmodel "github.com/SIOS-Technology-Inc/go-fiap-client/pkg/fiap/model"
+
+	"github.com/cockroachdb/errors"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// retryRandom returns the random number from 0 to 1 choosing the jitter of backoffs.
+var retryRandom = rand.Float64
+
+// retrySleep waits for the duration, or returns the error of the context when it is done first.
+var retrySleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retry calls the request until it succeeds or fails with an error not retried by the retry policy of the settings,
+// and returns the result of the last attempt and the number of attempts.
+func (cli *ClientImpl) retry(ctx context.Context, request func() (*fiapmodel.Error, error)) (fiapErr *fiapmodel.Error, attempts int, err error) {
+	maxAttempts := 1
+	if cli.Settings != nil && cli.Settings.Retry.Enabled() {
+		maxAttempts = cli.Settings.Retry.MaxAttempts
+	}
+	for attempts = 1; ; attempts++ {
+		fiapErr, err = request()
+		if attempts >= maxAttempts || !cli.retryable(ctx, fiapErr, err) {
+			return fiapErr, attempts, err
+		}
+		backoff := cli.Settings.Retry.Backoff(attempts, retryRandom())
+		backend.Logger.Warn("Retry the failed request", "attempt", attempts, "backoff", backoff, "error", err, "fiapError", fiapErr)
+		if sleepErr := retrySleep(ctx, backoff); sleepErr != nil {
+			return fiapErr, attempts, errors.CombineErrors(err, sleepErr)
+		}
+	}
+}
+
+// retryable returns whether the failure is transient, or the FIAP error of the type listed in the retry policy.
+func (cli *ClientImpl) retryable(ctx context.Context, fiapErr *fiapmodel.Error, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if err != nil {
+		return isTransientError(err)
+	}
+	if fiapErr != nil {
+		return cli.Settings.Retry.RetriesFiapError(fiapErr.Type)
+	}
+	return false
+}
+
+// isTransientError returns whether the error is a network error or a server error of HTTP,
+// which may not happen on the next request. Client errors of HTTP and the errors of the certificates,
+// the TLS handshake and the URL are never transient, since the same request fails again.
+func isTransientError(err error) bool {
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) {
+		switch statusErr.statusCode {
+		case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		default:
+			return false
+		}
+	}
+	// errors of http.Client.Do are url.Error, which is a net.Error itself even when it wraps an unsupported scheme,
+	// so that only the error it wraps is checked.
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	if isTLSError(err) {
+		return false
+	}
+	// the errors of reading a response body cut off are io.ErrUnexpectedEOF or net.Error,
+	// and a connection closed before the response is io.EOF.
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// isTLSError returns whether the error is of the verification of the certificates or the TLS handshake,
+// which are wrong settings of the server or the datasource rather than failures of the network.
+func isTLSError(err error) bool {
+	var (
+		verifyErr    *tls.CertificateVerificationError
+		alertErr     tls.AlertError
+		recordErr    tls.RecordHeaderError
+		authorityErr x509.UnknownAuthorityError
+		invalidErr   x509.CertificateInvalidError
+		hostnameErr  x509.HostnameError
+		rootsErr     x509.SystemRootsError
+	)
+	return errors.As(err, &verifyErr) || errors.As(err, &alertErr) || errors.As(err, &recordErr) ||
+		errors.As(err, &authorityErr) || errors.As(err, &invalidErr) || errors.As(err, &hostnameErr) ||
+		errors.As(err, &rootsErr)
+}
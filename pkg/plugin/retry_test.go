@@ -0,0 +1,259 @@
+package plugin
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/cockroachdb/errors"
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+const testErrorQueryRS = `<?xml version="1.0" encoding="UTF-8"?>
+<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/">
+<soapenv:Body>
+<ns2:queryRS xmlns:ns2="http://soap.fiap.org/">
+<transport xmlns="http://gutp.jp/fiap/2009/11/">
+<header><error type="%s">test error</error></header>
+</transport>
+</ns2:queryRS>
+</soapenv:Body>
+</soapenv:Envelope>`
+
+func TestFetchWithDateRangeRetry(t *testing.T) {
+	fromTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	toTime := time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC)
+	originalSleep, originalRandom := retrySleep, retryRandom
+	defer func() {
+		retrySleep, retryRandom = originalSleep, originalRandom
+	}()
+	backoffs := []time.Duration{}
+	retrySleep = func(_ context.Context, d time.Duration) error {
+		backoffs = append(backoffs, d)
+		return nil
+	}
+	retryRandom = func() float64 { return 0.5 }
+
+	// fetch requests the server which responds in the order of the responses, repeating the last one.
+	fetch := func(t *testing.T, policy dsmodel.RetryPolicy, responses ...fakeResponse) (*backend.DataResponse, int, error) {
+		t.Helper()
+		backoffs = backoffs[:0]
+		server := newFakeFiapServer(t, withResponses(responses...))
+		cli, err := CreateFiapApiClient(&dsmodel.FiapDatasourceSettings{Url: server.URL, Retry: policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := &backend.DataResponse{}
+		err = cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, []dsmodel.PointID{{Value: "id_a"}}, &backend.DataQuery{RefID: "A"}, nil)
+		return resp, len(server.requests()), err
+	}
+	policy := dsmodel.RetryPolicy{MaxAttempts: 3, InitialBackoff: 100, Jitter: 0.2}
+
+	t.Run("Retried", func(t *testing.T) {
+		t.Run("ServiceUnavailable", func(t *testing.T) {
+			resp, requests, err := fetch(t, policy, fakeResponse{status: http.StatusServiceUnavailable})
+			if err == nil || !strings.Contains(err.Error(), "503") {
+				t.Errorf("expected error of the status 503 but %v", err)
+			}
+			if requests != 3 {
+				t.Errorf("expected 3 requests but %d", requests)
+			}
+			expected := []time.Duration{90 * time.Millisecond, 180 * time.Millisecond}
+			if len(backoffs) != len(expected) {
+				t.Fatalf("expected backoffs are %v but %v", expected, backoffs)
+			}
+			for i := range expected {
+				if backoffs[i] != expected[i] {
+					t.Errorf("expected backoff[%d] is %v but %v", i, expected[i], backoffs[i])
+				}
+			}
+			if len(resp.Frames) != 0 {
+				t.Errorf("expected no frames but %d", len(resp.Frames))
+			}
+		})
+		t.Run("TruncatedBody", func(t *testing.T) {
+			_, requests, err := fetch(t, policy, fakeResponse{truncated: true})
+			if err == nil || !strings.Contains(err.Error(), "unexpected EOF") {
+				t.Errorf("expected error of the unexpected EOF but %v", err)
+			}
+			if requests != 3 {
+				t.Errorf("expected 3 requests but %d", requests)
+			}
+		})
+		t.Run("FiapErrorType", func(t *testing.T) {
+			retried := policy
+			retried.FiapErrorTypes = []string{"SERVER_ERROR"}
+			_, requests, err := fetch(t, retried, fiapErrorResponse("SERVER_ERROR"), fiapErrorResponse("SERVER_ERROR"), fiapErrorResponse("POINT_NOT_FOUND"))
+			if err == nil || !strings.Contains(err.Error(), "POINT_NOT_FOUND") {
+				t.Errorf("expected error of POINT_NOT_FOUND but %v", err)
+			}
+			if requests != 3 {
+				t.Errorf("expected 3 requests but %d", requests)
+			}
+		})
+	})
+	t.Run("NotRetried", func(t *testing.T) {
+		cases := []struct {
+			name      string
+			policy    dsmodel.RetryPolicy
+			responses []fakeResponse
+		}{
+			{"ClientError", policy, []fakeResponse{{status: http.StatusBadRequest}, {}}},
+			{"Unauthorized", policy, []fakeResponse{{status: http.StatusUnauthorized}, {}}},
+			{"RequestTimeout", policy, []fakeResponse{{status: http.StatusRequestTimeout}, {}}},
+			{"TooManyRequests", policy, []fakeResponse{{status: http.StatusTooManyRequests}, {}}},
+			{"FiapErrorType", policy, []fakeResponse{fiapErrorResponse("SERVER_ERROR"), {}}},
+			{"Disabled", dsmodel.RetryPolicy{}, []fakeResponse{{status: http.StatusServiceUnavailable}, {}}},
+		}
+		for _, c := range cases {
+			t.Run(c.name, func(t *testing.T) {
+				_, requests, err := fetch(t, c.policy, c.responses...)
+				if err == nil {
+					t.Error("expected error but nil")
+				}
+				if requests != 1 {
+					t.Errorf("expected 1 request but %d", requests)
+				}
+			})
+		}
+	})
+	t.Run("UnknownAuthority", func(t *testing.T) {
+		backoffs = backoffs[:0]
+		server := newFakeFiapServer(t, withTLS(&tls.Config{}))
+		cli, err := CreateFiapApiClient(&dsmodel.FiapDatasourceSettings{Url: server.URL, Retry: policy})
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp := &backend.DataResponse{}
+		if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, []dsmodel.PointID{{Value: "id_a"}}, &backend.DataQuery{RefID: "A"}, nil); err == nil {
+			t.Error("expected error of the certificate but nil")
+		}
+		if len(backoffs) != 0 {
+			t.Errorf("expected no retries but the backoffs %v", backoffs)
+		}
+	})
+	t.Run("AttemptsMeta", func(t *testing.T) {
+		attemptsOf := func(t *testing.T, policy dsmodel.RetryPolicy, failures int) interface{} {
+			t.Helper()
+			responses := make([]fakeResponse, failures, failures+1)
+			for i := range responses {
+				responses[i] = fakeResponse{status: http.StatusBadGateway}
+			}
+			server := newFakeFiapServer(t, withResponses(append(responses, fakeResponse{})...))
+			cli, err := CreateFiapApiClient(&dsmodel.FiapDatasourceSettings{Url: server.URL, Retry: policy})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp := &backend.DataResponse{}
+			if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, []dsmodel.PointID{{Value: "id_a"}}, &backend.DataQuery{RefID: "A"}, nil); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Frames[0].Meta == nil {
+				return nil
+			}
+			return resp.Frames[0].Meta.Custom.(map[string]interface{})["attempts"]
+		}
+		if attempts := attemptsOf(t, policy, 2); attempts != 3 {
+			t.Errorf("expected attempts are %d but %v", 3, attempts)
+		}
+		if attempts := attemptsOf(t, policy, 0); attempts != 1 {
+			t.Errorf("expected attempts are %d but %v", 1, attempts)
+		}
+		if attempts := attemptsOf(t, dsmodel.RetryPolicy{}, 0); attempts != nil {
+			t.Errorf("expected no attempts without retries but %v", attempts)
+		}
+	})
+	t.Run("Canceled", func(t *testing.T) {
+		retrySleep = originalSleep
+		defer func() {
+			retrySleep = func(_ context.Context, d time.Duration) error {
+				backoffs = append(backoffs, d)
+				return nil
+			}
+		}()
+		server := newFakeFiapServer(t, withStatus(http.StatusServiceUnavailable))
+		cli, err := CreateFiapApiClient(&dsmodel.FiapDatasourceSettings{Url: server.URL, Retry: dsmodel.RetryPolicy{MaxAttempts: 5, InitialBackoff: 60000}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		resp := &backend.DataResponse{}
+		if err := cli.FetchWithDateRange(ctx, resp, dsmodel.Period, &fromTime, &toTime, []dsmodel.PointID{{Value: "id_a"}}, &backend.DataQuery{RefID: "A"}, nil); err == nil {
+			t.Error("expected error of the cancellation but nil")
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("expected the backoff is aborted by the cancellation but it took %v", elapsed)
+		}
+	})
+}
+
+func TestIsTransientError(t *testing.T) {
+	_, malformedURLErr := url.Parse("http://[::1")
+	cases := []struct {
+		name     string
+		err      error
+		expected bool
+	}{
+		{"ServerError", &httpStatusError{statusCode: http.StatusBadGateway}, true},
+		{"RequestTimeout", &httpStatusError{statusCode: http.StatusRequestTimeout}, false},
+		{"TooManyRequests", &httpStatusError{statusCode: http.StatusTooManyRequests}, false},
+		{"ClientError", &httpStatusError{statusCode: http.StatusNotFound}, false},
+		{"Transport", &url.Error{Op: "Post", URL: "http://test.url", Err: &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}}, true},
+		{"ConnectionClosed", &url.Error{Op: "Post", URL: "http://test.url", Err: io.EOF}, true},
+		{"UnknownAuthority", &url.Error{Op: "Post", URL: "https://test.url", Err: &tls.CertificateVerificationError{Err: x509.UnknownAuthorityError{}}}, false},
+		{"Hostname", &url.Error{Op: "Post", URL: "https://test.url", Err: x509.HostnameError{Host: "test.url"}}, false},
+		{"TLSAlert", &url.Error{Op: "Post", URL: "https://test.url", Err: &net.OpError{Op: "remote error", Err: tls.AlertError(42)}}, false},
+		{"NotTLS", &url.Error{Op: "Post", URL: "https://test.url", Err: tls.RecordHeaderError{Msg: "first record does not look like a TLS handshake"}}, false},
+		{"UnsupportedScheme", &url.Error{Op: "Post", URL: "ftp://test.url", Err: errors.New("unsupported protocol scheme \"ftp\"")}, false},
+		{"MalformedURL", malformedURLErr, false},
+		{"BodyCutOff", errors.Wrap(io.ErrUnexpectedEOF, "fetch"), true},
+		{"BodyReadTimeout", &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, true},
+		{"Message", errors.New("cannot unmarshal"), false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if actual := isTransientError(c.err); actual != c.expected {
+				t.Errorf("expected %v but %v", c.expected, actual)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := dsmodel.RetryPolicy{MaxAttempts: 10, InitialBackoff: 100, MaxBackoff: 1000, Jitter: 0.5}
+	cases := []struct {
+		attempt  int
+		random   float64
+		expected time.Duration
+	}{
+		{1, 0, 100 * time.Millisecond},
+		{2, 0, 200 * time.Millisecond},
+		{4, 0, 800 * time.Millisecond},
+		{5, 0, 1000 * time.Millisecond},
+		{3, 1, 200 * time.Millisecond},
+		{5, 0.5, 750 * time.Millisecond},
+	}
+	for _, c := range cases {
+		if actual := policy.Backoff(c.attempt, c.random); actual != c.expected {
+			t.Errorf("expected backoff of attempt %d with random %v is %v but %v", c.attempt, c.random, c.expected, actual)
+		}
+	}
+	for _, invalid := range []dsmodel.RetryPolicy{{MaxAttempts: -1}, {InitialBackoff: -1}, {Jitter: 1.5}} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("expected error of %+v but nil", invalid)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package plugin
+
+import (
+	"sort"
+	"time"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/cockroachdb/errors"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// counterTransform is the conversion of counter values applied by transformFrames.
+type counterTransform struct {
+	transform dsmodel.TransformType
+	// rateUnit is the unit of time of rates.
+	rateUnit time.Duration
+	// interval is the buckets of increases, aligned in location.
+	interval dsmodel.Interval
+	location *time.Location
+	// maxValue is the value where the counter rolls over, or nil when the counter does not roll over.
+	maxValue *float64
+}
+
+// transformFrames replaces the values of the frames with the differences of the counter values.
+// The first value of each frame is left out because it has no previous value.
+func transformFrames(frames data.Frames, transform *counterTransform) error {
+	transformErrors := make([]error, 0)
+	for _, frame := range frames {
+		if err := transformFrame(frame, transform); err != nil {
+			transformErrors = append(transformErrors, errors.Wrapf(err, "transform frame '%s'", frame.Name))
+		}
+	}
+	return errors.Join(transformErrors...)
+}
+
+// counterSample is a row of a counter, whose value is nil when the value is not a number.
+type counterSample struct {
+	time  time.Time
+	value *float64
+}
+
+func transformFrame(frame *data.Frame, transform *counterTransform) error {
+	if len(frame.Fields) < 2 || frame.Fields[0].Type() != data.FieldTypeTime {
+		return errors.New("frame has no time field")
+	}
+	// frames of points without samples are left as they are, since they have no differences.
+	if frame.Rows() == 0 {
+		return nil
+	}
+	timeField, valueField := frame.Fields[0], frame.Fields[1]
+	if !valueField.Type().Numeric() {
+		return errors.Newf("transform '%s' needs numbers, but field '%s' is %s", transform.transform, valueField.Name, valueField.Type().ItemTypeString())
+	}
+
+	samples := make([]counterSample, frame.Rows())
+	for i := range samples {
+		value, err := valueField.NullableFloatAt(i)
+		if err != nil {
+			return err
+		}
+		samples[i] = counterSample{time: timeField.At(i).(time.Time), value: value}
+	}
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].time.Before(samples[j].time)
+	})
+
+	var (
+		times  []time.Time
+		values []*float64
+	)
+	switch transform.transform {
+	case dsmodel.TransformIncrease:
+		times, values = transform.increases(samples)
+	default:
+		times, values = transform.deltas(samples)
+	}
+
+	// fields of the values which are not numbers are dropped, since they are not aligned with the differences.
+	transformed := data.NewField(valueField.Name, valueField.Labels, values)
+	transformed.Config = valueField.Config
+	frame.Fields = []*data.Field{data.NewField(timeField.Name, timeField.Labels, times), transformed}
+	return nil
+}
+
+// deltas returns the difference of each value from the previous number, or the rate of it.
+// Values which are not numbers stay null.
+func (t *counterTransform) deltas(samples []counterSample) ([]time.Time, []*float64) {
+	times := make([]time.Time, 0, len(samples))
+	values := make([]*float64, 0, len(samples))
+	var previous *counterSample
+	for i := range samples {
+		sample := &samples[i]
+		if sample.value == nil {
+			if previous != nil {
+				times, values = append(times, sample.time), append(values, nil)
+			}
+			continue
+		}
+		if previous == nil {
+			previous = sample
+			continue
+		}
+		delta := t.counterDelta(*previous.value, *sample.value)
+		if t.transform == dsmodel.TransformRate {
+			elapsed := sample.time.Sub(previous.time)
+			if elapsed <= 0 {
+				times, values = append(times, sample.time), append(values, nil)
+				previous = sample
+				continue
+			}
+			delta = delta * float64(t.rateUnit) / float64(elapsed)
+		}
+		times, values = append(times, sample.time), append(values, &delta)
+		previous = sample
+	}
+	return times, values
+}
+
+// increases returns the sum of the differences in each bucket of the interval.
+// The difference between two values is counted in the bucket of the later value.
+func (t *counterTransform) increases(samples []counterSample) ([]time.Time, []*float64) {
+	times := make([]time.Time, 0)
+	values := make([]*float64, 0)
+	var previous *float64
+	for _, sample := range samples {
+		if sample.value == nil {
+			continue
+		}
+		start := t.interval.Truncate(sample.time.In(t.location))
+		if len(times) == 0 || !times[len(times)-1].Equal(start) {
+			times, values = append(times, start), append(values, pointerOf(0.0))
+		}
+		if previous != nil {
+			*values[len(values)-1] += t.counterDelta(*previous, *sample.value)
+		}
+		previous = sample.value
+	}
+	return times, values
+}
+
+// counterDelta returns the difference of the counter from previous to current.
+// A decrease is a rollover over the max value when it is set and wrapping over it is shorter than going back,
+// which means previous is close to the max value. Otherwise it is a reset to zero.
+func (t *counterTransform) counterDelta(previous float64, current float64) float64 {
+	if current >= previous {
+		return current - previous
+	}
+	if t.maxValue != nil && previous <= *t.maxValue {
+		if rollover := *t.maxValue - previous + current; rollover < previous-current {
+			return rollover
+		}
+	}
+	return current
+}
@@ -0,0 +1,142 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestTransformFrames(t *testing.T) {
+	baseTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	daily, err := dsmodel.ParseInterval("1d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a counter reset or rolled over between 12:00 and 18:00.
+	newFrame := func() *data.Frame {
+		return data.NewFrame("A:id_a",
+			data.NewField("time", nil, []time.Time{
+				baseTime.Add(6 * time.Hour),
+				baseTime,
+				baseTime.Add(12 * time.Hour),
+				baseTime.Add(18 * time.Hour),
+				baseTime.Add(30 * time.Hour),
+			}),
+			data.NewField("id_a", nil, []float64{40, 10, 90, 20, 50}).SetConfig(&data.FieldConfig{DisplayNameFromDS: "alias"}),
+		)
+	}
+	checkValues := func(t *testing.T, frame *data.Frame, expectedTimes []time.Time, expectedValues []*float64) {
+		t.Helper()
+		if frame.Rows() != len(expectedTimes) {
+			t.Fatalf("expected rows are %d but %d", len(expectedTimes), frame.Rows())
+		}
+		for i := range expectedTimes {
+			if actual := frame.Fields[0].At(i).(time.Time); !actual.Equal(expectedTimes[i]) {
+				t.Errorf("expected time[%d] is %s but %s", i, expectedTimes[i], actual)
+			}
+			actual := frame.Fields[1].At(i).(*float64)
+			if expectedValues[i] == nil && actual != nil {
+				t.Errorf("expected value[%d] is nil but %v", i, *actual)
+			} else if expectedValues[i] != nil && (actual == nil || *actual != *expectedValues[i]) {
+				t.Errorf("expected value[%d] is %v but %v", i, *expectedValues[i], actual)
+			}
+		}
+	}
+	t.Run("Normal", func(t *testing.T) {
+		t.Run("DeltaReset", func(t *testing.T) {
+			frame := newFrame()
+			if err := transformFrames(data.Frames{frame}, &counterTransform{transform: dsmodel.TransformDelta}); err != nil {
+				t.Fatal(err)
+			}
+			checkValues(t, frame,
+				[]time.Time{baseTime.Add(6 * time.Hour), baseTime.Add(12 * time.Hour), baseTime.Add(18 * time.Hour), baseTime.Add(30 * time.Hour)},
+				[]*float64{pointerOf(30.0), pointerOf(50.0), pointerOf(20.0), pointerOf(30.0)})
+			if frame.Fields[1].Config == nil || frame.Fields[1].Config.DisplayNameFromDS != "alias" {
+				t.Errorf("expected config is kept but %v", frame.Fields[1].Config)
+			}
+		})
+		t.Run("DeltaRollover", func(t *testing.T) {
+			frame := newFrame()
+			if err := transformFrames(data.Frames{frame}, &counterTransform{transform: dsmodel.TransformDelta, maxValue: pointerOf(100.0)}); err != nil {
+				t.Fatal(err)
+			}
+			checkValues(t, frame,
+				[]time.Time{baseTime.Add(6 * time.Hour), baseTime.Add(12 * time.Hour), baseTime.Add(18 * time.Hour), baseTime.Add(30 * time.Hour)},
+				[]*float64{pointerOf(30.0), pointerOf(50.0), pointerOf(30.0), pointerOf(30.0)})
+		})
+		t.Run("DeltaResetWithMaxValue", func(t *testing.T) {
+			// the counter reset from 50 to 3 since 50 is far from the max value.
+			frame := data.NewFrame("A:id_a",
+				data.NewField("time", nil, []time.Time{baseTime, baseTime.Add(time.Hour), baseTime.Add(2 * time.Hour)}),
+				data.NewField("id_a", nil, []float64{40, 50, 3}),
+			)
+			if err := transformFrames(data.Frames{frame}, &counterTransform{transform: dsmodel.TransformDelta, maxValue: pointerOf(100.0)}); err != nil {
+				t.Fatal(err)
+			}
+			checkValues(t, frame,
+				[]time.Time{baseTime.Add(time.Hour), baseTime.Add(2 * time.Hour)},
+				[]*float64{pointerOf(10.0), pointerOf(3.0)})
+		})
+		t.Run("Rate", func(t *testing.T) {
+			frame := newFrame()
+			if err := transformFrames(data.Frames{frame}, &counterTransform{transform: dsmodel.TransformRate, rateUnit: time.Hour}); err != nil {
+				t.Fatal(err)
+			}
+			checkValues(t, frame,
+				[]time.Time{baseTime.Add(6 * time.Hour), baseTime.Add(12 * time.Hour), baseTime.Add(18 * time.Hour), baseTime.Add(30 * time.Hour)},
+				[]*float64{pointerOf(5.0), pointerOf(50.0 / 6), pointerOf(20.0 / 6), pointerOf(2.5)})
+		})
+		t.Run("Increase", func(t *testing.T) {
+			frame := newFrame()
+			if err := transformFrames(data.Frames{frame}, &counterTransform{transform: dsmodel.TransformIncrease, interval: daily, location: time.UTC}); err != nil {
+				t.Fatal(err)
+			}
+			checkValues(t, frame,
+				[]time.Time{baseTime, baseTime.Add(24 * time.Hour)},
+				[]*float64{pointerOf(100.0), pointerOf(30.0)})
+		})
+		t.Run("EmptyPoint", func(t *testing.T) {
+			frame := data.NewFrame("A:id_empty",
+				data.NewField("time", nil, []time.Time{}),
+				data.NewField("id_empty", nil, []string{}),
+			)
+			if err := transformFrames(data.Frames{frame}, &counterTransform{transform: dsmodel.TransformDelta}); err != nil {
+				t.Fatal(err)
+			}
+			if frame.Rows() != 0 {
+				t.Errorf("expected rows are %d but %d", 0, frame.Rows())
+			}
+		})
+		t.Run("NullValues", func(t *testing.T) {
+			frame := data.NewFrame("A:id_a",
+				data.NewField("time", nil, []time.Time{baseTime, baseTime.Add(time.Hour), baseTime.Add(2 * time.Hour)}),
+				data.NewField("id_a", nil, []*float64{pointerOf(1.0), nil, pointerOf(4.0)}),
+				data.NewField("id_a"+rejectedFieldSuffix, nil, []*string{nil, pointerOf("error"), nil}),
+			)
+			if err := transformFrames(data.Frames{frame}, &counterTransform{transform: dsmodel.TransformDelta}); err != nil {
+				t.Fatal(err)
+			}
+			checkValues(t, frame,
+				[]time.Time{baseTime.Add(time.Hour), baseTime.Add(2 * time.Hour)},
+				[]*float64{nil, pointerOf(3.0)})
+			if len(frame.Fields) != 2 {
+				t.Errorf("expected rejected values are dropped but %d fields", len(frame.Fields))
+			}
+		})
+	})
+	t.Run("Error", func(t *testing.T) {
+		frame := data.NewFrame("A:id_a",
+			data.NewField("time", nil, []time.Time{baseTime}),
+			data.NewField("id_a", nil, []string{"on"}),
+		)
+		if err := transformFrames(data.Frames{frame}, &counterTransform{transform: dsmodel.TransformDelta}); err == nil {
+			t.Errorf("expected error is %s but nil", "needs numbers")
+		} else if !strings.Contains(err.Error(), "needs numbers") {
+			t.Errorf("expected error is %s but %s", "needs numbers", err.Error())
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package plugin
+
+import (
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/cockroachdb/errors"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// applyUnit sets the unit of the point to the value field, converting the values into the display unit.
+// The unit given by the query overrides the one registered in the settings.
+// Fields which are not numbers, such as mapped values, are left as they are.
+func (cli *ClientImpl) applyUnit(valueField *data.Field, pointID string, pointUnit dsmodel.PointUnit) error {
+	unit := pointUnit
+	if cli.Settings != nil {
+		unit = pointUnit.Merge(cli.Settings.PointUnits[pointID])
+	}
+	if unit.Unit == "" && unit.DisplayUnit == "" {
+		return nil
+	}
+	if valueField.Type() != data.FieldTypeFloat64 && valueField.Type() != data.FieldTypeNullableFloat64 {
+		return nil
+	}
+	convert, err := unit.Converter()
+	if err != nil {
+		return errors.Wrapf(err, "unit of point id '%s'", pointID)
+	}
+	for i := 0; i < valueField.Len(); i++ {
+		switch value := valueField.At(i).(type) {
+		case float64:
+			valueField.Set(i, convert(value))
+		case *float64:
+			if value != nil {
+				valueField.Set(i, pointerOf(convert(*value)))
+			}
+		}
+	}
+	if valueField.Config == nil {
+		valueField.Config = &data.FieldConfig{}
+	}
+	valueField.Config.Unit = unit.GrafanaUnit()
+	return nil
+}
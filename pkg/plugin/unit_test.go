@@ -0,0 +1,114 @@
+package plugin
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	fiapmodel "github.com/SIOS-Technology-Inc/go-fiap-client/pkg/fiap/model"
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestFetchWithDateRangeUnit(t *testing.T) {
+	query := &backend.DataQuery{
+		RefID: "A",
+	}
+	fromTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	toTime := time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC)
+	cli, _ := newDateRangeClient(map[string][]fiapmodel.Value{
+		"id_power":  valuesOf(1, "1500", "250"),
+		"id_temp":   valuesOf(1, "212", "32"),
+		"id_status": valuesOf(1, "ON", "OFF"),
+	}, &dsmodel.FiapDatasourceSettings{
+		PointUnits: map[string]dsmodel.PointUnit{
+			"id_power": {Unit: "W", DisplayUnit: "kW"},
+			"id_temp":  {Unit: "°F"},
+		},
+	})
+	fetch := func(pointID dsmodel.PointID) (*backend.DataResponse, error) {
+		resp := &backend.DataResponse{}
+		err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, []dsmodel.PointID{pointID}, query, &dsmodel.QueryOptions{NullableNumbers: true})
+		return resp, err
+	}
+	checkValues := func(t *testing.T, resp *backend.DataResponse, unit string, expected ...float64) {
+		t.Helper()
+		valueField := resp.Frames[0].Fields[1]
+		if valueField.Config == nil || valueField.Config.Unit != unit {
+			t.Errorf("expected unit is %s but %v", unit, valueField.Config)
+		}
+		for i := range expected {
+			if actual := valueField.At(i).(*float64); actual == nil || math.Abs(*actual-expected[i]) > 1e-9 {
+				t.Errorf("expected value[%d] is %v but %v", i, expected[i], actual)
+			}
+		}
+	}
+
+	t.Run("Normal", func(t *testing.T) {
+		t.Run("Registry", func(t *testing.T) {
+			resp, err := fetch(dsmodel.PointID{Value: "id_power"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkValues(t, resp, "kwatt", 1.5, 0.25)
+		})
+		t.Run("WithoutConversion", func(t *testing.T) {
+			resp, err := fetch(dsmodel.PointID{Value: "id_temp"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkValues(t, resp, "fahrenheit", 212, 32)
+		})
+		t.Run("QueryOverride", func(t *testing.T) {
+			resp, err := fetch(dsmodel.PointID{Value: "id_temp", PointUnit: dsmodel.PointUnit{DisplayUnit: "celsius"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkValues(t, resp, "celsius", 100, 0)
+		})
+		t.Run("UnknownUnit", func(t *testing.T) {
+			resp, err := fetch(dsmodel.PointID{Value: "id_power", PointUnit: dsmodel.PointUnit{Unit: "percent", DisplayUnit: "percent"}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkValues(t, resp, "percent", 1500, 250)
+		})
+	})
+	t.Run("Error", func(t *testing.T) {
+		_, err := fetch(dsmodel.PointID{Value: "id_power", PointUnit: dsmodel.PointUnit{DisplayUnit: "°C"}})
+		if err == nil {
+			t.Errorf("expected error is %s but nil", "can not be converted")
+		} else if !strings.Contains(err.Error(), "can not be converted") {
+			t.Errorf("expected error is %s but %s", "can not be converted", err.Error())
+		}
+	})
+}
+
+func TestPointUnitConverter(t *testing.T) {
+	cases := []struct {
+		unit     dsmodel.PointUnit
+		value    float64
+		expected float64
+	}{
+		{dsmodel.PointUnit{Unit: "kW", DisplayUnit: "W"}, 1.5, 1500},
+		{dsmodel.PointUnit{Unit: "Wh", DisplayUnit: "kwatth"}, 2500, 2.5},
+		{dsmodel.PointUnit{Unit: "°C", DisplayUnit: "°F"}, 100, 212},
+		{dsmodel.PointUnit{Unit: "fahrenheit", DisplayUnit: "celsius"}, 50, 10},
+		{dsmodel.PointUnit{Unit: "kPa", DisplayUnit: "Pa"}, 101.325, 101325},
+	}
+	for _, c := range cases {
+		convert, err := c.unit.Converter()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := convert(c.value); math.Abs(actual-c.expected) > 1e-9 {
+			t.Errorf("expected %v %s is %v %s but %v", c.value, c.unit.Unit, c.expected, c.unit.DisplayUnit, actual)
+		}
+	}
+	if _, err := (&dsmodel.PointUnit{Unit: "W", DisplayUnit: "kWh"}).Converter(); err == nil {
+		t.Error("expected error of incompatible units but nil")
+	}
+}
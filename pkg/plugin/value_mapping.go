@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	fiapmodel "github.com/SIOS-Technology-Inc/go-fiap-client/pkg/fiap/model"
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// valueMappingOf returns the mapping of the point, or the default mapping of the settings when the values are not numbers.
+func (cli *ClientImpl) valueMappingOf(pointMapping *dsmodel.ValueMapping, pointArray []fiapmodel.Value) *dsmodel.ValueMapping {
+	if pointMapping != nil {
+		return pointMapping
+	}
+	if cli.Settings == nil || cli.Settings.DefaultValueMapping == nil {
+		return nil
+	}
+	if _, _, err := pointsToFloatColumns(pointArray); err == nil {
+		return nil
+	}
+	return cli.Settings.DefaultValueMapping
+}
+
+// pointsToMappedColumns converts the values with the mapping into nullable booleans or nullable numeric codes.
+// It also returns the value mappings of Grafana which show the original values on panels.
+func pointsToMappedColumns(pointArray []fiapmodel.Value, mapping *dsmodel.ValueMapping) ([]time.Time, interface{}, data.ValueMappings) {
+	times := make([]time.Time, len(pointArray))
+	for i := range pointArray {
+		times[i] = pointArray[i].Time
+	}
+
+	switch mapping.Type {
+	case dsmodel.BooleanMapping:
+		values := make([]*bool, len(pointArray))
+		for i := range pointArray {
+			if containsValue(mapping.True, pointArray[i].Value) {
+				values[i] = pointerOf(true)
+			} else if containsValue(mapping.False, pointArray[i].Value) {
+				values[i] = pointerOf(false)
+			}
+		}
+		mapper := data.ValueMapper{}
+		if len(mapping.True) > 0 {
+			mapper["true"] = data.ValueMappingResult{Text: mapping.True[0], Index: 0}
+		}
+		if len(mapping.False) > 0 {
+			mapper["false"] = data.ValueMappingResult{Text: mapping.False[0], Index: 1}
+		}
+		return times, values, data.ValueMappings{mapper}
+	default:
+		labels := sortedKeys(mapping.Codes)
+		values := make([]*float64, len(pointArray))
+		for i := range pointArray {
+			for _, label := range labels {
+				if matchValue(label, pointArray[i].Value) {
+					values[i] = pointerOf(mapping.Codes[label])
+					break
+				}
+			}
+		}
+		// the first label in order is shown when labels share a code.
+		mapper := data.ValueMapper{}
+		for _, label := range labels {
+			code := strconv.FormatFloat(mapping.Codes[label], 'f', -1, 64)
+			if _, ok := mapper[code]; !ok {
+				mapper[code] = data.ValueMappingResult{Text: label, Index: len(mapper)}
+			}
+		}
+		return times, values, data.ValueMappings{mapper}
+	}
+}
+
+func containsValue(candidates []string, value string) bool {
+	for _, candidate := range candidates {
+		if matchValue(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchValue(candidate string, value string) bool {
+	return strings.EqualFold(strings.TrimSpace(candidate), strings.TrimSpace(value))
+}
+
+func pointerOf[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	fiapmodel "github.com/SIOS-Technology-Inc/go-fiap-client/pkg/fiap/model"
+	dsmodel "github.com/sios/fiap/pkg/model"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestFetchWithDateRangeValueMapping(t *testing.T) {
+	query := &backend.DataQuery{
+		RefID: "A",
+	}
+	fromTime := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	toTime := time.Date(2024, 5, 31, 23, 59, 59, 0, time.UTC)
+	cli, _ := newDateRangeClient(map[string][]fiapmodel.Value{
+		"id_switch": valuesOf(1, "ON", " off ", "??"),
+		"id_status": valuesOf(1, "RUN", "STOP", "FAULT", "run"),
+		"id_temp":   valuesOf(1, "21.5", "22"),
+	}, &dsmodel.FiapDatasourceSettings{
+		DefaultValueMapping: &dsmodel.ValueMapping{Type: dsmodel.BooleanMapping, True: []string{"ON", "true"}, False: []string{"OFF", "false"}},
+	})
+
+	t.Run("Boolean", func(t *testing.T) {
+		resp := &backend.DataResponse{}
+		// the default mapping of the settings is used.
+		if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, []dsmodel.PointID{{Value: "id_switch"}}, query, &dsmodel.QueryOptions{}); err != nil {
+			t.Error(err)
+		}
+		valueField := resp.Frames[0].Fields[1]
+		if valueField.Type() != data.FieldTypeNullableBool {
+			t.Fatalf("expected type is %s but %s", data.FieldTypeNullableBool, valueField.Type())
+		}
+		for i, expected := range []*bool{pointerOf(true), pointerOf(false), nil} {
+			if actual := valueField.At(i).(*bool); (actual == nil) != (expected == nil) || (actual != nil && *actual != *expected) {
+				t.Errorf("expected value[%d] is %v but %v", i, expected, actual)
+			}
+		}
+		if valueField.Config == nil || len(valueField.Config.Mappings) != 1 {
+			t.Fatal("expected one value mapping in the field config")
+		}
+		mapper := valueField.Config.Mappings[0].(data.ValueMapper)
+		if mapper["true"].Text != "ON" || mapper["false"].Text != "OFF" {
+			t.Errorf("expected texts are %s and %s but %v", "ON", "OFF", mapper)
+		}
+	})
+	t.Run("Enum", func(t *testing.T) {
+		resp := &backend.DataResponse{}
+		pointIDs := []dsmodel.PointID{{Value: "id_status", Alias: "status", ValueMapping: &dsmodel.ValueMapping{
+			Type:  dsmodel.EnumMapping,
+			Codes: map[string]float64{"STOP": 0, "RUN": 1, "FAULT": 2},
+		}}}
+		if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, pointIDs, query, &dsmodel.QueryOptions{}); err != nil {
+			t.Error(err)
+		}
+		valueField := resp.Frames[0].Fields[1]
+		if valueField.Type() != data.FieldTypeNullableFloat64 {
+			t.Fatalf("expected type is %s but %s", data.FieldTypeNullableFloat64, valueField.Type())
+		}
+		for i, expected := range []float64{1, 0, 2, 1} {
+			if actual := valueField.At(i).(*float64); actual == nil || *actual != expected {
+				t.Errorf("expected value[%d] is %v but %v", i, expected, actual)
+			}
+		}
+		mapper := valueField.Config.Mappings[0].(data.ValueMapper)
+		for code, expected := range map[string]string{"0": "STOP", "1": "RUN", "2": "FAULT"} {
+			if mapper[code].Text != expected {
+				t.Errorf("expected text of %s is %s but %s", code, expected, mapper[code].Text)
+			}
+		}
+		if valueField.Config.DisplayNameFromDS != "status" {
+			t.Errorf("expected display name is %s but %s", "status", valueField.Config.DisplayNameFromDS)
+		}
+	})
+	t.Run("NumericPointWithDefault", func(t *testing.T) {
+		resp := &backend.DataResponse{}
+		if err := cli.FetchWithDateRange(context.Background(), resp, dsmodel.Period, &fromTime, &toTime, []dsmodel.PointID{{Value: "id_temp"}}, query, &dsmodel.QueryOptions{}); err != nil {
+			t.Error(err)
+		}
+		if valueField := resp.Frames[0].Fields[1]; valueField.Type() != data.FieldTypeFloat64 {
+			t.Errorf("expected type is %s but %s", data.FieldTypeFloat64, valueField.Type())
+		}
+	})
+}
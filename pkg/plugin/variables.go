@@ -0,0 +1,96 @@
+package plugin
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sios/fiap/pkg/model"
+)
+
+// variablePattern matches `$name`, `${name}`, `${name:format}`, `[[name]]` and `[[name:format]]`.
+// Formats are accepted but ignored, since each point ID receives a single value.
+var variablePattern = regexp.MustCompile(`\$(\w+)|\$\{(\w+)(?::[^}]*)?\}|\[\[(\w+)(?::[^\]]*)?\]\]`)
+
+// interpolatePointIDs replaces template variables in the point IDs and aliases with their values.
+// A point ID referring to multi-value variables is expanded into one point ID per combination of the values.
+// Variables not found in vars are left as they are.
+func interpolatePointIDs(pointIDs []model.PointID, vars map[string]model.ScopedVar) []model.PointID {
+	if len(vars) == 0 {
+		return pointIDs
+	}
+
+	retVal := make([]model.PointID, 0, len(pointIDs))
+	for _, pointID := range pointIDs {
+		for _, assignment := range variableAssignments(referredVariables(vars, pointID.Value, pointID.Alias), vars) {
+			expanded := pointID
+			expanded.Value = interpolate(pointID.Value, assignment)
+			expanded.Alias = interpolate(pointID.Alias, assignment)
+			retVal = append(retVal, expanded)
+		}
+	}
+	return retVal
+}
+
+// referredVariables returns the names of the known variables in the texts, in the order of the first appearance.
+func referredVariables(vars map[string]model.ScopedVar, texts ...string) []string {
+	names := make([]string, 0)
+	for _, text := range texts {
+		for _, match := range variablePattern.FindAllStringSubmatch(text, -1) {
+			name := variableName(match)
+			if _, ok := vars[name]; !ok {
+				continue
+			}
+			found := false
+			for _, n := range names {
+				found = found || n == name
+			}
+			if !found {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// variableAssignments returns all combinations of the values of the variables.
+func variableAssignments(names []string, vars map[string]model.ScopedVar) []map[string]string {
+	assignments := []map[string]string{{}}
+	for _, name := range names {
+		scopedVar := vars[name]
+		values := scopedVar.GetValues()
+		next := make([]map[string]string, 0, len(assignments)*len(values))
+		for _, assignment := range assignments {
+			for _, value := range values {
+				combined := make(map[string]string, len(assignment)+1)
+				for k, v := range assignment {
+					combined[k] = v
+				}
+				combined[name] = value
+				next = append(next, combined)
+			}
+		}
+		assignments = next
+	}
+	return assignments
+}
+
+func interpolate(text string, assignment map[string]string) string {
+	if !strings.ContainsAny(text, "$[") {
+		return text
+	}
+	return variablePattern.ReplaceAllStringFunc(text, func(matched string) string {
+		if value, ok := assignment[variableName(variablePattern.FindStringSubmatch(matched))]; ok {
+			return value
+		}
+		return matched
+	})
+}
+
+func variableName(match []string) string {
+	for _, name := range match[1:] {
+		if name != "" {
+			return name
+		}
+	}
+	return ""
+}
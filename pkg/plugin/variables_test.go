@@ -0,0 +1,79 @@
+package plugin
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sios/fiap/pkg/model"
+)
+
+func TestInterpolatePointIDs(t *testing.T) {
+	var vars map[string]model.ScopedVar
+	if err := json.Unmarshal([]byte(`{
+		"building": {"text": "Building 1", "value": "building1"},
+		"floor": {"text": "2", "value": 2},
+		"sensors": {"text": "temp + hum", "value": ["temp", "hum"]},
+		"rooms": {"text": "r1 + r2", "value": ["r1", "r2"]}
+	}`), &vars); err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name             string
+		pointIDs         []model.PointID
+		expectedPointIDs []model.PointID
+	}{
+		{
+			name:             "NoVariable",
+			pointIDs:         []model.PointID{{Value: "http://example.com/a"}},
+			expectedPointIDs: []model.PointID{{Value: "http://example.com/a"}},
+		},
+		{
+			name:             "SingleValue",
+			pointIDs:         []model.PointID{{Value: "http://example.com/$building/${floor}/[[building:raw]]", Alias: "${building:text}"}},
+			expectedPointIDs: []model.PointID{{Value: "http://example.com/building1/2/building1", Alias: "building1"}},
+		},
+		{
+			name:     "MultiValue",
+			pointIDs: []model.PointID{{Value: "http://example.com/$building/$sensors", DataRange: model.Latest, Alias: "$sensors"}, {Value: "http://example.com/b"}},
+			expectedPointIDs: []model.PointID{
+				{Value: "http://example.com/building1/temp", DataRange: model.Latest, Alias: "temp"},
+				{Value: "http://example.com/building1/hum", DataRange: model.Latest, Alias: "hum"},
+				{Value: "http://example.com/b"},
+			},
+		},
+		{
+			name:     "MultipleMultiValues",
+			pointIDs: []model.PointID{{Value: "http://example.com/$rooms/$sensors/$rooms"}},
+			expectedPointIDs: []model.PointID{
+				{Value: "http://example.com/r1/temp/r1"},
+				{Value: "http://example.com/r1/hum/r1"},
+				{Value: "http://example.com/r2/temp/r2"},
+				{Value: "http://example.com/r2/hum/r2"},
+			},
+		},
+		{
+			name:             "UnknownVariable",
+			pointIDs:         []model.PointID{{Value: "http://example.com/$unknown/${building}"}},
+			expectedPointIDs: []model.PointID{{Value: "http://example.com/$unknown/building1"}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual := interpolatePointIDs(c.pointIDs, vars)
+			if len(actual) != len(c.expectedPointIDs) {
+				t.Fatalf("expected pointIDs' length is %d but %d", len(c.expectedPointIDs), len(actual))
+			}
+			for i := range actual {
+				if actual[i].Value != c.expectedPointIDs[i].Value {
+					t.Errorf("expected pointID[%d] is %s but %s", i, c.expectedPointIDs[i].Value, actual[i].Value)
+				}
+				if actual[i].Alias != c.expectedPointIDs[i].Alias {
+					t.Errorf("expected alias of pointID[%d] is %s but %s", i, c.expectedPointIDs[i].Alias, actual[i].Alias)
+				}
+				if actual[i].DataRange != c.expectedPointIDs[i].DataRange {
+					t.Errorf("expected datarange of pointID[%d] is %s but %s", i, c.expectedPointIDs[i].DataRange, actual[i].DataRange)
+				}
+			}
+		})
+	}
+}
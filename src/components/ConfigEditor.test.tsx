@@ -90,12 +90,12 @@ describe('ConfigEditor', () => {
         render(<ConfigEditor onOptionsChange={onOptionsChange} options={testOptions} />);
 
         await waitFor(() => {
-          expect(screen.queryByText('Invalid timezone format. Please use the format ±HH:MM. For example, +09:00 or -05:30.')).not.toBeInTheDocument();
+          expect(screen.queryByText('Invalid timezone. Please use an offset like +09:00 or -05:30, or an IANA timezone name like Asia/Tokyo.')).not.toBeInTheDocument();
         });
       });
     });
     describe('when server timezone is invalid', () => {
-      const InputTimezones = ['+0012', '-0012', '+13:00', '-13:00', '+00:60', '-00:60', '+00:0', '-00:0', '+0:00', '-0:00', '+0:0', '-0:0','+12','-12']
+      const InputTimezones = ['+0012', '-0012', '+15:00', '-15:00', '+00:60', '-00:60', '+00:0', '-00:0', '+0:00', '-0:00', '+0:0', '-0:0','+12','-12', 'Asia/Nowhere', 'Europe/', '/Berlin']
       it.each(InputTimezones)('should show error message (input: %s)', async (inputTimezone) => {
         render(<ConfigEditor onOptionsChange={onOptionsChange} options={testOptions} />);
 
@@ -104,12 +104,12 @@ describe('ConfigEditor', () => {
         await userEvent.type(input, inputTimezone);
         
         await waitFor(() => {
-          expect(screen.queryByText('Invalid timezone format. Please use the format ±HH:MM. For example, +09:00 or -05:30.')).toBeInTheDocument();
+          expect(screen.queryByText('Invalid timezone. Please use an offset like +09:00 or -05:30, or an IANA timezone name like Asia/Tokyo.')).toBeInTheDocument();
         });
       });
     });
     describe('when server timezone is valid', () => {
-      const InputTimezones = ['+00:00', '-00:00', '+12:59', '-12:59', '+00:30', '-00:30', '+09:00', '-09:00', '+05:30', '-05:30']
+      const InputTimezones = ['+00:00', '-00:00', '+12:59', '-12:59', '+00:30', '-00:30', '+09:00', '-09:00', '+05:30', '-05:30', '+13:00', '+14:00', 'UTC', 'Asia/Tokyo', 'Europe/Berlin', 'America/Argentina/Buenos_Aires']
       it.each(InputTimezones)('should not show error message (input: %s)', async (inputTimezone) => {
         render(<ConfigEditor onOptionsChange={onOptionsChange} options={testOptions} />);
 
@@ -118,9 +118,88 @@ describe('ConfigEditor', () => {
         await userEvent.type(input, inputTimezone);
         
         await waitFor(() => {
-          expect(screen.queryByText('Invalid timezone format. Please use the format ±HH:MM. For example, +09:00 or -05:30.')).not.toBeInTheDocument();
+          expect(screen.queryByText('Invalid timezone. Please use an offset like +09:00 or -05:30, or an IANA timezone name like Asia/Tokyo.')).not.toBeInTheDocument();
         });
       });
     });
   });
+  describe('secure settings test', () => {
+    describe('when the bearer token is configured', () => {
+      it('should reset the token', async () => {
+        const onChange = jest.fn();
+        const options = {
+          ...testOptions,
+          jsonData: { ...testOptions.jsonData, auth_type: 'bearer' as const },
+          secureJsonFields: { bearer_token: true },
+        };
+        render(<ConfigEditor onOptionsChange={onChange} options={options} />);
+
+        await userEvent.click(screen.getByRole('button', { name: /reset/i }));
+
+        expect(onChange).toHaveBeenLastCalledWith(expect.objectContaining({
+          secureJsonFields: { bearer_token: false },
+          secureJsonData: { bearer_token: '' },
+        }));
+      });
+    });
+    describe('when the proxy password is entered', () => {
+      it('should set the secure json data', async () => {
+        const onChange = jest.fn();
+        render(<ConfigEditor onOptionsChange={onChange} options={testOptions} />);
+
+        await userEvent.type(screen.getByLabelText(/proxy password/i), 'p');
+
+        expect(onChange).toHaveBeenLastCalledWith(expect.objectContaining({
+          secureJsonData: { proxy_password: 'p' },
+        }));
+      });
+    });
+  });
+  describe('custom headers test', () => {
+    describe('when a header is added', () => {
+      it('should set the name of the next index', async () => {
+        const onChange = jest.fn();
+        const options = { ...testOptions, jsonData: { ...testOptions.jsonData, httpHeaderName1: 'X-Tenant-Id' } };
+        render(<ConfigEditor onOptionsChange={onChange} options={options} />);
+
+        await userEvent.click(screen.getByRole('button', { name: /add header/i }));
+
+        expect(onChange).toHaveBeenLastCalledWith(expect.objectContaining({
+          jsonData: expect.objectContaining({ httpHeaderName1: 'X-Tenant-Id', httpHeaderName2: '' }),
+        }));
+      });
+    });
+    describe('when the header value is entered', () => {
+      it('should set the secure json data of the same index', async () => {
+        const onChange = jest.fn();
+        const options = { ...testOptions, jsonData: { ...testOptions.jsonData, httpHeaderName3: 'X-Tenant-Id' } };
+        render(<ConfigEditor onOptionsChange={onChange} options={options} />);
+
+        expect(screen.getByLabelText('Header name 3')).toHaveValue('X-Tenant-Id');
+        await userEvent.type(screen.getByLabelText('Header value 3'), 'p');
+
+        expect(onChange).toHaveBeenLastCalledWith(expect.objectContaining({
+          secureJsonData: { httpHeaderValue3: 'p' },
+        }));
+      });
+    });
+    describe('when a header is removed', () => {
+      it('should remove the name and the value', async () => {
+        const onChange = jest.fn();
+        const options = {
+          ...testOptions,
+          jsonData: { ...testOptions.jsonData, httpHeaderName1: 'X-Tenant-Id' },
+          secureJsonFields: { httpHeaderValue1: true },
+        };
+        render(<ConfigEditor onOptionsChange={onChange} options={options} />);
+
+        await userEvent.click(screen.getByRole('button', { name: 'Remove header 1' }));
+
+        const changed = onChange.mock.lastCall[0];
+        expect(changed.jsonData).not.toHaveProperty('httpHeaderName1');
+        expect(changed.secureJsonFields).toEqual({ httpHeaderValue1: false });
+        expect(changed.secureJsonData).toEqual({ httpHeaderValue1: '' });
+      });
+    });
+  });
 });
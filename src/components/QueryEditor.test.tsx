@@ -190,10 +190,16 @@ describe('QueryEditor', () => {
       '2000-01-01 00:a0:00',
       '2000-01-01 00:00:a0',
       '2000/01/01 00:00:00',
-      '2000-01-01 00-00-00'
+      '2000-01-01 00-00-00',
+      'now-7',
+      'now-d',
+      'now/',
+      'now-7x',
+      '$__from-',
+      'yesterday'
     ];
     const invalidTimeInputs = ['2000-13-01 00:00:00', '2000-12-32'];
-    const validTimeInputs = ['2000-01-01 00:00:00', '2000-01-01'];
+    const validTimeInputs = ['2000-01-01 00:00:00', '2000-01-01', 'now', 'now-7d', 'now/d', 'now-1M/M', '$__from-1d', '$__to+1h'];
 
     describe('when start time and end time is empty in the initial state', () => {
       it('should not show date error message', async () => {
@@ -213,7 +219,7 @@ describe('QueryEditor', () => {
           await userEvent.type(screen.getByTestId('start-time-input'), invalidFormatTimeInput);
   
           await waitFor(() => {
-            expect(screen.queryByText("Invalid date format. Please use 'YYYY-MM-DD HH:MM:SS' or 'YYYY-MM-DD' format.")).toBeInTheDocument();
+            expect(screen.queryByText("Invalid date format. Please use 'YYYY-MM-DD HH:MM:SS' or 'YYYY-MM-DD' format, or a relative time like 'now-7d'.")).toBeInTheDocument();
           });
         });
       });
@@ -251,7 +257,7 @@ describe('QueryEditor', () => {
           await userEvent.type(screen.getByTestId('end-time-input'), invalidFormatTimeInput);
   
           await waitFor(() => {
-            expect(screen.queryByText("Invalid date format. Please use 'YYYY-MM-DD HH:MM:SS' or 'YYYY-MM-DD' format.")).toBeInTheDocument();
+            expect(screen.queryByText("Invalid date format. Please use 'YYYY-MM-DD HH:MM:SS' or 'YYYY-MM-DD' format, or a relative time like 'now-7d'.")).toBeInTheDocument();
           });
         });
       });
@@ -339,4 +345,31 @@ describe('QueryEditor', () => {
       })
     });
   });
+  describe('query options test', () => {
+    describe('when time shift is entered', () => {
+      it('should keep the other options', async () => {
+        const onChange = jest.fn();
+        const query: MyQuery = { ...testQuery, aggregation: 'avg', point_ids: [{ point_id: 'id_a', alias: 'A' }] };
+        render(<QueryEditor query={query} onChange={onChange} onRunQuery={testonRunQuery} datasource={testDatasource}/>);
+
+        await userEvent.type(screen.getByLabelText(/time shift/i), 'w');
+
+        expect(onChange).toHaveBeenLastCalledWith(expect.objectContaining({
+          time_shift: 'w',
+          aggregation: 'avg',
+          point_ids: [{ point_id: 'id_a', alias: 'A' }],
+        }));
+      });
+    });
+    describe('when point sets are expanded', () => {
+      it('should set expand_point_sets', async () => {
+        const onChange = jest.fn();
+        render(<QueryEditor query={testQuery} onChange={onChange} onRunQuery={testonRunQuery} datasource={testDatasource}/>);
+
+        await userEvent.click(screen.getByLabelText(/expand point sets/i));
+
+        expect(onChange).toHaveBeenLastCalledWith(expect.objectContaining({ expand_point_sets: true }));
+      });
+    });
+  });
 });
\ No newline at end of file
@@ -1,10 +1,10 @@
 import React ,{ useEffect } from 'react';
 import { useForm, useFieldArray, Controller } from 'react-hook-form';
 
-import { InlineFieldRow, InlineField, Input, Button, Checkbox, RadioButtonList } from '@grafana/ui';
-import { QueryEditorProps } from '@grafana/data';
+import { InlineFieldRow, InlineField, InlineSwitch, Input, Button, Checkbox, RadioButtonList, Select } from '@grafana/ui';
+import { QueryEditorProps, SelectableValue } from '@grafana/data';
 import { DataSource } from '../datasource';
-import { MyDataSourceOptions, MyQuery } from '../types';
+import { FiapAggregationType, FiapDownsamplingType, FiapFillMode, FiapFrameLayout, MyDataSourceOptions, MyQuery } from '../types';
 
 import { css } from '@emotion/css';
 
@@ -12,14 +12,20 @@ type Props = QueryEditorProps<DataSource, MyQuery, MyDataSourceOptions>;
 
 const dateTimeFormat = /^\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}$/;
 const dateFormat = /^\d{4}-\d{2}-\d{2}$/;
+// now-7d、now/d、$__from-1dのような相対時刻。単位はs、m、h、d、w、M、y
+const relativeTimeFormat = /^(now|\$__from|\$\{__from\}|\$__to|\$\{__to\})([+-]\d+[smhdwMy]|\/[smhdwMy])*$/;
 
 const isValidDateTime = (value: string) => {
   if (value === '') {
     return true;
   }
 
+  if (relativeTimeFormat.test(value)) {
+    return true;
+  }
+
   if (!dateTimeFormat.test(value) && !dateFormat.test(value)) {
-    return "Invalid date format. Please use 'YYYY-MM-DD HH:MM:SS' or 'YYYY-MM-DD' format.";
+    return "Invalid date format. Please use 'YYYY-MM-DD HH:MM:SS' or 'YYYY-MM-DD' format, or a relative time like 'now-7d'.";
   }
 
   const dateTime = new Date(value);
@@ -30,6 +36,33 @@ const isValidDateTime = (value: string) => {
   return true;
 };
 
+const aggregationOptions: Array<SelectableValue<FiapAggregationType>> = [
+  { label: 'Average', value: 'avg' },
+  { label: 'Min', value: 'min' },
+  { label: 'Max', value: 'max' },
+  { label: 'Sum', value: 'sum' },
+  { label: 'Count', value: 'count' },
+  { label: 'First', value: 'first' },
+  { label: 'Last', value: 'last' },
+];
+
+const fillOptions: Array<SelectableValue<FiapFillMode>> = [
+  { label: 'Null', value: 'null' },
+  { label: 'Previous', value: 'previous' },
+  { label: 'Linear', value: 'linear' },
+];
+
+const downsamplingOptions: Array<SelectableValue<FiapDownsamplingType>> = [
+  { label: 'LTTB', value: 'lttb' },
+  { label: 'Min/Max', value: 'minmax' },
+];
+
+const frameLayoutOptions: Array<SelectableValue<FiapFrameLayout>> = [
+  { label: 'Multi', value: 'multi' },
+  { label: 'Wide', value: 'wide' },
+  { label: 'Long', value: 'long' },
+];
+
 const pointValidationRule = {
   required: 'This field is required',
 }
@@ -156,11 +189,11 @@ export function QueryEditor({ query, onChange }: Props) {
           control={control}
           rules={{ validate: isValidDateTime }}
           render={({ field, fieldState: { error } }) => (
-            <InlineField label="Start time" labelWidth={16} tooltip={"It uses server timezone from datasource settings."} invalid={Boolean(error)} error={error && error.message}>
+            <InlineField label="Start time" labelWidth={16} tooltip={"YYYY-MM-DD HH:MM:SS, or a relative time like now-7d, now/d or $__from-1d. It uses server timezone from datasource settings."} invalid={Boolean(error)} error={error && error.message}>
             <div style={{ pointerEvents: startLinkDashboards ? 'none' : 'auto', opacity: startLinkDashboards ? 0.4 : 1 }}>
               <Input
                 id={`start_time`}
-                placeholder="YYYY-MM-DD HH:MM:SS"
+                placeholder="YYYY-MM-DD HH:MM:SS or now-7d"
                 value={field.value}
                 onChange={(e) => {
                   field.onChange(e.currentTarget.value);
@@ -194,11 +227,11 @@ export function QueryEditor({ query, onChange }: Props) {
                 control={control}
                 rules={{ validate: isValidDateTime }}
                 render={({ field ,fieldState:{ error }}) => (
-                  <InlineField label="End time" labelWidth={16} tooltip={"It uses server timezone from datasource settings."} invalid={Boolean(error)} error={error && error.message}>
+                  <InlineField label="End time" labelWidth={16} tooltip={"YYYY-MM-DD HH:MM:SS, or a relative time like now-7d, now/d or $__from-1d. It uses server timezone from datasource settings."} invalid={Boolean(error)} error={error && error.message}>
                   <div style={{ pointerEvents: endLinkDashboards ? 'none' : 'auto', opacity: endLinkDashboards ? 0.4 : 1 }}>
                   <Input
                     id={`end_time`}
-                    placeholder="YYYY-MM-DD HH:MM:SS"
+                    placeholder="YYYY-MM-DD HH:MM:SS or now-7d"
                     value={field.value}
                     onChange={(e) => {
                       field.onChange(e.currentTarget.value);
@@ -226,6 +259,99 @@ export function QueryEditor({ query, onChange }: Props) {
           )}
         />
       </InlineFieldRow>
+      {/* ポイントごとの上書きや値の変換などの他のオプションはクエリのJSONで指定する */}
+      <InlineFieldRow>
+        <InlineField label="Time shift" labelWidth={16} tooltip="Shifts the time range back, like 1d or 1w. The timestamps are moved forward by the same amount.">
+          <Input
+            id="time_shift"
+            value={query.time_shift || ''}
+            onChange={(e) => onChange({ ...query, time_shift: e.currentTarget.value || undefined })}
+            placeholder="1d"
+            width={12}
+          />
+        </InlineField>
+        <InlineField label="Timezone" labelWidth={12} tooltip="Overrides the server timezone of the datasource settings for this query.">
+          <Input
+            id="timezone"
+            value={query.timezone || ''}
+            onChange={(e) => onChange({ ...query, timezone: e.currentTarget.value || undefined })}
+            placeholder="+09:00 or Asia/Tokyo"
+            width={24}
+          />
+        </InlineField>
+      </InlineFieldRow>
+      <InlineFieldRow>
+        <InlineField label="Aggregation" labelWidth={16}>
+          <Select
+            inputId="aggregation"
+            options={aggregationOptions}
+            value={query.aggregation ?? null}
+            onChange={(v) => onChange({ ...query, aggregation: v?.value })}
+            isClearable
+            width={16}
+          />
+        </InlineField>
+        <InlineField label="Interval" labelWidth={12} tooltip="Width of the aggregation, like 15m or 1d. The interval of the query is used if it is empty.">
+          <Input
+            id="aggregation_interval"
+            value={query.aggregation_interval || ''}
+            onChange={(e) => onChange({ ...query, aggregation_interval: e.currentTarget.value || undefined })}
+            placeholder="15m"
+            width={12}
+          />
+        </InlineField>
+        <InlineField label="Fill" labelWidth={8} tooltip="Fills the gaps of the values.">
+          <Select
+            inputId="fill"
+            options={fillOptions}
+            value={query.fill ?? null}
+            onChange={(v) => onChange({ ...query, fill: v?.value })}
+            isClearable
+            width={16}
+          />
+        </InlineField>
+      </InlineFieldRow>
+      <InlineFieldRow>
+        <InlineField label="Downsampling" labelWidth={16} tooltip="Thins out the series with more values than the max data points of the panel.">
+          <Select
+            inputId="downsampling"
+            options={downsamplingOptions}
+            value={query.downsampling ?? null}
+            onChange={(v) => onChange({ ...query, downsampling: v?.value })}
+            isClearable
+            width={16}
+          />
+        </InlineField>
+        <InlineField label="Frame layout" labelWidth={14}>
+          <Select
+            inputId="frame_layout"
+            options={frameLayoutOptions}
+            value={query.frame_layout ?? null}
+            onChange={(v) => onChange({ ...query, frame_layout: v?.value })}
+            isClearable
+            placeholder="Multi"
+            width={16}
+          />
+        </InlineField>
+        <InlineField label="Expand point sets" labelWidth={18} tooltip="Fetches the points under the point sets of the point IDs.">
+          <InlineSwitch
+            id="expand_point_sets"
+            value={query.expand_point_sets ?? false}
+            onChange={(e) => onChange({ ...query, expand_point_sets: e.currentTarget.checked || undefined })}
+          />
+        </InlineField>
+      </InlineFieldRow>
+      <InlineFieldRow>
+        <InlineField label="Alias template" labelWidth={16} tooltip="Display name of the series without an alias, like {{segment -1}}. {{point_id}}, {{ref_id}} and {{segment N}} are replaced.">
+          <Input
+            id="alias_template"
+            value={query.alias_template || ''}
+            onChange={(e) => onChange({ ...query, alias_template: e.currentTarget.value || undefined })}
+            placeholder="{{point_id}}"
+            width={52}
+          />
+        </InlineField>
+      </InlineFieldRow>
     </>
   );
 }
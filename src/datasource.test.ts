@@ -0,0 +1,49 @@
+import { DataSourceInstanceSettings } from '@grafana/data';
+
+import { DataSource } from './datasource';
+import { MyDataSourceOptions, MyQuery } from './types';
+
+const variables: Record<string, { text: string; json: string }> = {
+  building: { text: 'b1', json: '"b1"' },
+  sensors: { text: 'temp + hum', json: '["temp","hum"]' },
+};
+
+jest.mock('@grafana/runtime', () => ({
+  ...jest.requireActual('@grafana/runtime'),
+  getTemplateSrv: () => ({
+    getVariables: () => [{ name: 'building' }, { name: 'sensors' }],
+    replace: (target: string, scopedVars?: Record<string, { text: string; value: string }>) => {
+      const [, name, format] = target.match(/^\$\{(\w+):(\w+)\}$/) ?? [];
+      const scoped = scopedVars?.[name];
+      if (scoped) {
+        return format === 'json' ? JSON.stringify(scoped.value) : scoped.text;
+      }
+      const variable = variables[name];
+      if (!variable) {
+        return target;
+      }
+      return format === 'json' ? variable.json : variable.text;
+    },
+  }),
+}));
+
+describe('DataSource', () => {
+  const datasource = new DataSource({ jsonData: {} } as DataSourceInstanceSettings<MyDataSourceOptions>);
+  const query: MyQuery = {
+    refId: 'A',
+    point_ids: [{ point_id: '$building/$sensors/$floor' }],
+    data_range: 'period',
+    start_time: { time: '', link_dashboard: true },
+    end_time: { time: '', link_dashboard: true },
+  };
+
+  it('forwards the dashboard variables and the scoped variables', () => {
+    const applied = datasource.applyTemplateVariables(query, { floor: { text: 'F2', value: 'f2' } });
+    expect(applied.scoped_vars).toEqual({
+      building: { text: 'b1', value: 'b1' },
+      sensors: { text: 'temp + hum', value: ['temp', 'hum'] },
+      floor: { text: 'F2', value: 'f2' },
+    });
+    expect(applied.point_ids).toEqual(query.point_ids);
+  });
+});
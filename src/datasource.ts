@@ -1,7 +1,7 @@
-import { DataSourceInstanceSettings, CoreApp } from '@grafana/data';
-import { DataSourceWithBackend } from '@grafana/runtime';
+import { DataSourceInstanceSettings, CoreApp, ScopedVars } from '@grafana/data';
+import { DataSourceWithBackend, getTemplateSrv } from '@grafana/runtime';
 
-import { MyQuery, MyDataSourceOptions, DEFAULT_QUERY } from './types';
+import { MyQuery, MyDataSourceOptions, DEFAULT_QUERY, FiapScopedVar } from './types';
 
 export class DataSource extends DataSourceWithBackend<MyQuery, MyDataSourceOptions> {
   constructor(instanceSettings: DataSourceInstanceSettings<MyDataSourceOptions>) {
@@ -11,4 +11,26 @@ export class DataSource extends DataSourceWithBackend<MyQuery, MyDataSourceOptio
   getDefaultQuery(_: CoreApp): Partial<MyQuery> {
     return DEFAULT_QUERY;
   }
+
+  /**
+   * Forwards the values of the dashboard variables and the scoped variables such as the ones of repeated panels,
+   * since Grafana does not send them to the backend.
+   */
+  applyTemplateVariables(query: MyQuery, scopedVars: ScopedVars): MyQuery {
+    const templateSrv = getTemplateSrv();
+    const names = new Set<string>([...templateSrv.getVariables().map((v) => v.name), ...Object.keys(scopedVars ?? {})]);
+    const vars: Record<string, FiapScopedVar> = {};
+    names.forEach((name) => {
+      const text = templateSrv.replace(`\${${name}:text}`, scopedVars);
+      // the json format resolves `All` and multiple values into an array.
+      const formatted = templateSrv.replace(`\${${name}:json}`, scopedVars);
+      try {
+        const value = JSON.parse(formatted);
+        vars[name] = { text, value: Array.isArray(value) ? value.map(String) : String(value) };
+      } catch {
+        vars[name] = { text, value: formatted };
+      }
+    });
+    return { ...query, scoped_vars: vars };
+  }
 }
@@ -2,16 +2,60 @@ import { DataSourceJsonData } from '@grafana/data';
 import { DataQuery } from '@grafana/schema';
 
 export interface MyQuery extends DataQuery {
-  point_ids: Array<{ point_id: string }>;
+  point_ids: FiapPointID[];
   data_range: string;
-  start_time: {
-    time: string;
-    link_dashboard: boolean;
-  };
-  end_time: {
-    time: string;
-    link_dashboard: boolean;
-  };
+  start_time: FiapLinkedTime;
+  end_time: FiapLinkedTime;
+  time_shift?: string;
+  timezone?: string;
+  scoped_vars?: Record<string, FiapScopedVar>;
+  transform?: FiapTransformType;
+  rate_unit?: 's' | 'm' | 'h';
+  counter_max_value?: number;
+  aggregation?: FiapAggregationType;
+  aggregation_interval?: string;
+  fill?: FiapFillMode;
+  fill_threshold?: string;
+  frame_layout?: FiapFrameLayout;
+  join_rounding?: string;
+  downsampling?: FiapDownsamplingType;
+  expand_point_sets?: boolean;
+  nullable_numbers?: boolean;
+  keep_rejected_values?: boolean;
+  alias_template?: string;
+  alias_pattern?: string;
+}
+
+export interface FiapLinkedTime {
+  time: string;
+  link_dashboard: boolean;
+}
+
+/**
+ * A point of the query. The optional fields override the ones of the query and the datasource for this point.
+ */
+export interface FiapPointID extends FiapPointUnit {
+  point_id: string;
+  data_range?: string;
+  start_time?: FiapLinkedTime;
+  end_time?: FiapLinkedTime;
+  alias?: string;
+  value_mapping?: FiapValueMapping;
+}
+
+export type FiapTransformType = 'delta' | 'rate' | 'increase';
+export type FiapAggregationType = 'avg' | 'min' | 'max' | 'sum' | 'count' | 'first' | 'last';
+export type FiapFillMode = 'null' | 'previous' | 'linear';
+export type FiapFrameLayout = 'multi' | 'wide' | 'long';
+export type FiapDownsamplingType = 'lttb' | 'minmax';
+
+/**
+ * A template variable forwarded to the backend, which interpolates it into the point IDs and aliases.
+ * The value is an array of strings for multi-value variables.
+ */
+export interface FiapScopedVar {
+  text: string;
+  value: string | string[];
 }
 
 export const DEFAULT_QUERY: Partial<MyQuery> = {
@@ -27,4 +71,57 @@ export const DEFAULT_QUERY: Partial<MyQuery> = {
 export interface MyDataSourceOptions extends DataSourceJsonData {
   url: string;
   server_timezone: string;
+  standby_urls?: string[];
+  failback_interval?: number;
+  acceptable_size?: number;
+  max_records?: number;
+  default_value_mapping?: FiapValueMapping;
+  point_units?: Record<string, FiapPointUnit>;
+  auth_type?: FiapAuthType;
+  basic_auth_user?: string;
+  tls_server_name?: string;
+  tls_skip_verify?: boolean;
+  proxy_url?: string;
+  proxy_user?: string;
+  // カスタムヘッダの名前。値はsecureJsonDataの同じ番号のhttpHeaderValueに保存する
+  [headerName: `httpHeaderName${number}`]: string | undefined;
+  connect_timeout?: number;
+  read_timeout?: number;
+  health_probe_point_id?: string;
+  retry?: FiapRetryPolicy;
+}
+
+/**
+ * Values of these keys are stored encrypted, and only the backend can read them
+ */
+export interface MySecureJsonData {
+  basic_auth_password?: string;
+  bearer_token?: string;
+  tls_ca_cert?: string;
+  tls_client_cert?: string;
+  tls_client_key?: string;
+  proxy_password?: string;
+  [headerValue: `httpHeaderValue${number}`]: string | undefined;
+}
+
+export type FiapAuthType = '' | 'basic' | 'bearer';
+
+export interface FiapValueMapping {
+  type: 'boolean' | 'enum';
+  true?: string[];
+  false?: string[];
+  codes?: Record<string, number>;
+}
+
+export interface FiapPointUnit {
+  unit?: string;
+  display_unit?: string;
+}
+
+export interface FiapRetryPolicy {
+  max_attempts?: number;
+  initial_backoff_ms?: number;
+  max_backoff_ms?: number;
+  jitter?: number;
+  fiap_error_types?: string[];
 }